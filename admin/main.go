@@ -5,58 +5,155 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 
-	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
-	glog "github.com/labstack/gommon/log"
 	"go-mqtt-demo/config"
+	"go-mqtt-demo/configstore"
+	"go-mqtt-demo/embedded"
 	"go-mqtt-demo/handler"
+	"go-mqtt-demo/history"
 	tmpl "go-mqtt-demo/html/template"
 	"go-mqtt-demo/logger"
+	"go-mqtt-demo/topic"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		glog.Fatal("error loading .env file")
+	cfg, err := config.New(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
 
-	logger.Init()
+	if err != nil {
+		logger.Fatal("failed to start", "error", err)
+	}
 
-	e := echo.New()
+	// The config is printed even if invalid, to find the source of the invalid value
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			logger.Fatal("failed to start", "error", err)
+		}
+
+		if err := cfg.Validate(); err != nil {
+			logger.Fatal("failed to start", "error", err)
+		}
+
+		return
+	}
+
+	logOpts := cfg.LogOptions()
+	logOpts.Attrs = []any{"location_id", cfg.LocationId}
 
-	cfg, err := config.New()
+	logs, err := logger.Init(logOpts)
 	if err != nil {
-		glog.Fatal(err)
+		logger.Fatal("failed to start", "error", err)
 	}
 
+	e := echo.New()
+	logger.Echo(e)
+
 	frontend(e, cfg)
 
-	const ca = "emqxsl-ca.crt"
+	// Closed in order on shutdown, after disconnecting from the broker
+	var closers []io.Closer
+
+	if cfg.EmbeddedBroker {
+		b, err := embedded.Start(
+			embedded.Options{TCPAddress: ":" + cfg.BrokerPort, WSAddress: ":" + cfg.BrokerWSPort},
+		)
+		if err != nil {
+			logger.Fatal("failed to start", "error", err)
+		}
+
+		closers = append(closers, b)
+	}
 
 	subTopic := fmt.Sprintf("location/%v/kiosk/+/sensor/#", cfg.LocationId)
 
-	h, err := handler.New(ca, "pub_cfg_client", "sub_sensors_client", subTopic)
+	store, err := history.Open(
+		cfg.HistoryStore, cfg.HistoryPath,
+		history.Retention{MaxCount: cfg.HistoryMaxCount, MaxAge: cfg.HistoryMaxAge},
+	)
 	if err != nil {
-		glog.Fatal(err)
+		logger.Fatal("failed to start", "error", err)
 	}
 
+	configs, err := configstore.Open(cfg.ConfigStore, cfg.ConfigStorePath)
+	if err != nil {
+		logger.Fatal("failed to start", "error", err)
+	}
+
+	h, err := handler.New(cfg.ClientOptions(), "pub_cfg_client", "sub_sensors_client", []string{subTopic}, "", store)
+	if err != nil {
+		logger.Fatal("failed to start", "error", err)
+	}
+
+	statusFilter := topic.KioskStatus(cfg.LocationId, "+")
+
+	p := handler.NewPresence(h, statusFilter, subTopic)
+	f := handler.NewFleet(h, p, cfg.LocationId, subTopic)
+	r := handler.NewRollout(h, f, topic.KioskConfigAck(cfg.LocationId, "+"), configs)
+	sh := handler.NewAdminShadow(h, cfg.LocationId)
+	rc := handler.NewRPC(h, cfg.LocationId, topic.AdminRPCResponse(cfg.LocationId, cfg.ClientIDSuffix))
+
 	if err := h.Connect(); err != nil {
-		glog.Fatal(err)
+		logger.Fatal("failed to start", "error", err)
 	}
 
-	e.POST("/config", h.Publish)
+	ll := handler.NewLogLevels(cfg.AdminToken)
+
+	reloader := startReloader(cfg, h, ll)
+
+	e.POST("/config", r.Publish)
+	e.GET("/config/status", r.List)
+	e.GET("/config/status/:version", r.Get)
+	e.GET("/config/versions", r.Versions)
+	e.GET("/config/versions/:version", r.Version)
+	e.POST("/config/versions/:version/rollback", r.Rollback)
+	e.GET("/config/diff", r.Diff)
 
 	e.GET("/sse/sensors", h.SubscribeSse)
 	e.GET("/ws/sensors", h.SubscribeWs)
 
-	handleShutdown(h)
+	e.GET("/history", h.History)
+	e.GET("/status", h.Status)
+
+	e.GET("/presence", p.List)
+	e.GET("/ws/presence", p.SubscribeWs)
+
+	e.GET("/fleet/kiosks", f.List)
+	e.GET("/fleet/kiosks/:id", f.Get)
+	e.DELETE("/fleet/kiosks/:id", f.Delete)
+
+	e.POST("/kiosk/:id/rpc/:method", rc.Call)
+
+	e.GET("/shadow", sh.List)
+	e.GET("/shadow/:id", sh.Get)
+	e.PUT("/shadow/:id/desired", sh.UpdateDesired)
+	e.GET("/ws/shadow", sh.SubscribeWs)
 
-	e.Logger.Fatal(e.Start(":" + cfg.ServicePort))
+	admin := e.Group("/admin", ll.Authorize())
+	admin.GET("/log/levels", ll.Get)
+	admin.PUT("/log/levels", ll.Update)
+
+	// The logs are closed last, to log the closing of the others
+	closers = append(closers, logs)
+
+	handleShutdown(h, append([]io.Closer{reloader, store, configs}, closers...)...)
+
+	slog.Info("starting service", "port", cfg.ServicePort)
+
+	if err := e.Start(":" + cfg.ServicePort); err != nil {
+		logger.Fatal("service stopped", "error", err)
+	}
 }
 
 func frontend(e *echo.Echo, cfg *config.Config) {
@@ -82,9 +179,46 @@ func frontend(e *echo.Echo, cfg *config.Config) {
 			return c.Render(http.StatusOK, "sub.html", data)
 		},
 	)
+	e.GET(
+		"/fleet", func(c echo.Context) error {
+			data := map[string]interface{}{
+				"LocId": cfg.LocationId,
+			}
+
+			return c.Render(http.StatusOK, "fleet.html", data)
+		},
+	)
 }
 
-func handleShutdown(h *handler.Handler) {
+// startReloader applies the config on SIGHUP or file change, reconnecting to the broker if its settings changed.
+func startReloader(cfg *config.Config, h *handler.Handler, ll *handler.LogLevels) *config.Reloader {
+	r := config.NewReloader(cfg)
+
+	// Reconnecting is the only step that may fail with a valid config, so it is last
+	r.OnReload(
+		func(_, next *config.Config) error {
+			logger.SetDebug(next.Debug)
+			ll.SetToken(next.AdminToken)
+
+			if err := logger.SetPayloadOptions(next.LogPayload); err != nil {
+				return err
+			}
+
+			return logger.SetLevels(next.LogLevels)
+		},
+	)
+	r.OnReload(
+		func(_, next *config.Config) error {
+			return h.Reconfigure(next.ClientOptions())
+		},
+	)
+
+	r.Start()
+
+	return r
+}
+
+func handleShutdown(h *handler.Handler, closers ...io.Closer) {
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
 
@@ -92,6 +226,13 @@ func handleShutdown(h *handler.Handler) {
 		<-sig
 
 		h.Disconnect()
+
+		for _, c := range closers {
+			if err := c.Close(); err != nil {
+				slog.Error("failed to close", "error", err)
+			}
+		}
+
 		os.Exit(0)
 	}()
 }
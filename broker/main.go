@@ -0,0 +1,58 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Command broker runs the embedded MQTT broker on its own, so admin and kiosks can share it on one machine.
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/joho/godotenv"
+	"go-mqtt-demo/embedded"
+	"go-mqtt-demo/logger"
+)
+
+const (
+	DefaultPort   = "1883"
+	DefaultWSPort = "8083"
+)
+
+func main() {
+	// The .env file is optional, since the ports have defaults
+	_ = godotenv.Load()
+
+	logOpts := logger.Options{Format: os.Getenv("LOG_FORMAT"), Debug: os.Getenv("DEBUG") == "1"}
+	logs, err := logger.Init(logOpts)
+	if err != nil {
+		logger.Fatal("failed to start", "error", err)
+	}
+
+	port := os.Getenv("BROKER_PORT")
+	if port == "" {
+		port = DefaultPort
+	}
+
+	wsPort := os.Getenv("BROKER_WS_PORT")
+	if wsPort == "" {
+		wsPort = DefaultWSPort
+	}
+
+	b, err := embedded.Start(embedded.Options{TCPAddress: ":" + port, WSAddress: ":" + wsPort})
+	if err != nil {
+		logger.Fatal("failed to start", "error", err)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+
+	<-sig
+
+	if err := b.Close(); err != nil {
+		slog.Error("failed to close", "error", err)
+	}
+
+	_ = logs.Close()
+}
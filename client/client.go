@@ -7,71 +7,193 @@ package client
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
+	"log/slog"
 	"net/url"
 	"os"
+	"sync"
 	"time"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-	glog "github.com/labstack/gommon/log"
+	"go-mqtt-demo/logger"
+	"go-mqtt-demo/secret"
 )
 
 const (
 	DefaultQuiesceTimeout = 250
 	DefaultBufferSize     = 256
+
+	// DefaultConnectTimeout bounds the initial connection attempt of an MQTT 5 session.
+	DefaultConnectTimeout = 30 * time.Second
+
+	// DefaultSessionExpiry keeps an MQTT 5 session alive on the broker while the client is offline,
+	// which is what a clean session of false does with MQTT 3.1.1.
+	DefaultSessionExpiry = 24 * 60 * 60
 )
 
-func setTLSConfig(opts *mqtt.ClientOptions, caName string) (*tls.Config, error) {
-	certpool := x509.NewCertPool()
+// The transports to the broker.
+const (
+	TransportTCP       = "tcp"
+	TransportWebSocket = "ws"
+)
+
+// newConnOptions builds the options of a connection to the brokers of the transport.
+func newConnOptions(o Options, clientId, transport string) (*connOptions, error) {
+	var (
+		brokers []*url.URL
+		err     error
+	)
+
+	if transport == TransportWebSocket {
+		brokers, err = brokerURLs(
+			o.BrokerWSURLs, o.BrokerAddress, brokerScheme(o.BrokerWSScheme, o.BrokerTLS, "ws", "wss"),
+			o.BrokerWSPort, "/mqtt",
+		)
+	} else {
+		brokers, err = brokerURLs(
+			o.BrokerURLs, o.BrokerAddress, brokerScheme(o.BrokerScheme, o.BrokerTLS, "tcp", "ssl"), o.BrokerPort, "",
+		)
+	}
 
-	ca, err := os.ReadFile(caName)
 	if err != nil {
 		return nil, err
 	}
 
-	certpool.AppendCertsFromPEM(ca)
+	opts := &connOptions{
+		version: o.ProtocolVersion,
+		brokers: newBrokerSet(brokers, o.BrokerFailover),
+
+		// The resulting client ID must be unique, otherwise the broker will reject the connection
+		clientId:             clientId + "_" + o.ClientIDSuffix,
+		username:             o.Username,
+		password:             newRevealer(o.Password),
+		cleanSession:         o.CleanSession,
+		maxReconnectInterval: o.MaxReconnectInterval,
+	}
+
+	opts.log = logger.For(logger.SubsystemClient).With("client_id", opts.clientId)
 
-	tc := &tls.Config{RootCAs: certpool}
-	opts.SetTLSConfig(tc)
+	if hasSecureScheme(brokers) {
+		if err := setTLSConfig(opts, o); err != nil {
+			return nil, err
+		}
+	}
 
-	return tc, nil
+	return opts, nil
 }
 
-func setAuth(clientId string, opts *mqtt.ClientOptions) {
-	// The resulting client ID must be unique, otherwise the broker will reject the connection
-	opts.SetClientID(clientId + "_" + os.Getenv("CLIENT_ID_SUFFIX"))
-	opts.SetUsername(os.Getenv("MQTT_USERNAME"))
-	opts.SetPassword(os.Getenv("MQTT_PASSWORD"))
+// brokerScheme returns the scheme if set, or else the plain or secure scheme depending on whether to use TLS.
+// Brokers on the same machine, such as the embedded broker, usually serve plain TCP and WebSocket.
+func brokerScheme(scheme string, useTLS bool, plain, secure string) string {
+	if scheme != "" {
+		return scheme
+	}
+
+	if useTLS {
+		return secure
+	}
+
+	return plain
 }
 
-func setCleanSession(opts *mqtt.ClientOptions) {
-	opts.CleanSession = os.Getenv("MQTT_CLEAN_SESSION") == "1"
+func isSecureScheme(scheme string) bool {
+	return scheme == "ssl" || scheme == "wss"
 }
 
-func setReconnect(opts *mqtt.ClientOptions) {
-	opts.SetAutoReconnect(true)
+// setTLSConfig trusts the CA of the file, or the system roots without a CA file. With SystemRoots, the CA is trusted
+// in addition to the system roots, e.g. for brokers behind both a private and a public CA. With a client certificate
+// and key, the client authenticates with mutual TLS.
+func setTLSConfig(opts *connOptions, o Options) error {
+	minVersion, err := parseTLSVersion(o.TLSMinVersion)
+	if err != nil {
+		return err
+	}
 
-	maxReconnectInterval, _ := time.ParseDuration(os.Getenv("MQTT_MAX_RECONNECT_INTERVAL"))
-	opts.SetMaxReconnectInterval(maxReconnectInterval)
-}
+	cipherSuites, err := parseCipherSuites(o.CipherSuites)
+	if err != nil {
+		return err
+	}
+
+	tlsCfg := &tls.Config{MinVersion: minVersion, CipherSuites: cipherSuites}
+
+	if o.ClientCertFile != "" {
+		r, err := newCertReloader(o.ClientCertFile, o.ClientKeyFile, newRevealer(o.ClientKeyPassword))
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		tlsCfg.GetClientCertificate = r.GetClientCertificate
+	}
 
-func defaultPublishHandler(_ mqtt.Client, msg mqtt.Message) {
-	glog.Infof("received from topic: %v\n>>\t%s", msg.Topic(), msg.Payload())
+	opts.tlsConfig = tlsCfg
+
+	// A nil pool uses the system roots
+	if o.CAFile == "" {
+		return nil
+	}
+
+	certpool := x509.NewCertPool()
+
+	if o.SystemRoots {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return fmt.Errorf("failed to load system roots: %w", err)
+		}
+
+		certpool = pool
+	}
+
+	ca, err := os.ReadFile(o.CAFile)
+	if err != nil {
+		return err
+	}
+
+	if !certpool.AppendCertsFromPEM(ca) {
+		return fmt.Errorf("no certificates found in %v", o.CAFile)
+	}
+
+	tlsCfg.RootCAs = certpool
+
+	return nil
 }
 
-func onConnectAttempt(broker *url.URL, tlsCfg *tls.Config) *tls.Config {
-	glog.Infof("connecting to broker %s...", broker.Host)
+// revealer reveals a secret on every connection, so a rotated secret is used from the next (re)connection. If the
+// secret cannot be read, the secret revealed last is kept.
+type revealer struct {
+	secret secret.Value
+
+	mu   sync.Mutex
+	last string
+}
 
-	return tlsCfg
+func newRevealer(v secret.Value) *revealer {
+	return &revealer{secret: v}
 }
 
-func onReconnecting(_ mqtt.Client, opts *mqtt.ClientOptions) {
-	glog.Infof("reconnecting to broker %s...", opts.Servers)
+func (r *revealer) reveal() string {
+	s, err := r.secret.Reveal()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		logger.For(logger.SubsystemClient).Error("failed to read secret, keeping the secret read last", "error", err)
+
+		return r.last
+	}
+
+	r.last = s
+
+	return s
 }
 
-func onConnect(_ mqtt.Client) {
-	glog.Info("connected to broker")
+// defaultPublishHandler logs the messages that no subscription handler matched.
+func defaultPublishHandler(log *slog.Logger) MessageHandler {
+	return func(msg *Message) {
+		log.Info("received message", messageAttrs(msg)...)
+	}
 }
 
-func onConnectionLost(_ mqtt.Client, err error) {
-	glog.Infof("connection to broker lost: %v", err)
+// messageAttrs returns the log attributes of a message. The payload is logged as set by logger.SetPayloadOptions.
+func messageAttrs(msg *Message) []any {
+	return append([]any{"topic", msg.Topic, "qos", msg.Qos, "retained", msg.Retained}, logger.PayloadAttrs(msg.Payload)...)
 }
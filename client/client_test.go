@@ -0,0 +1,87 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewConnOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		o         Options
+		transport string
+
+		brokers  []string
+		clientId string
+		version  ProtocolVersion
+		tls      bool
+	}{
+		{
+			name: "local broker",
+			o: Options{
+				BrokerAddress:        "localhost",
+				BrokerPort:           "1883",
+				BrokerWSPort:         "8083",
+				ClientIDSuffix:       "kiosk1",
+				MaxReconnectInterval: time.Second,
+				ProtocolVersion:      ProtocolV311,
+			},
+			transport: TransportWebSocket,
+			brokers:   []string{"ws://localhost:8083/mqtt"},
+			clientId:  "sub_client_kiosk1",
+			version:   ProtocolV311,
+		},
+		{
+			name: "broker cluster over tls",
+			o: Options{
+				BrokerTLS:            true,
+				BrokerURLs:           []string{"ssl://b1.example.com:8883", " ssl://b2.example.com:8883 "},
+				ClientIDSuffix:       "admin1",
+				MaxReconnectInterval: time.Second,
+				ProtocolVersion:      ProtocolV5,
+			},
+			transport: TransportTCP,
+			brokers:   []string{"ssl://b1.example.com:8883", "ssl://b2.example.com:8883"},
+			clientId:  "sub_client_admin1",
+			version:   ProtocolV5,
+			tls:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				opts, err := newConnOptions(tt.o, "sub_client", tt.transport)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var brokers []string
+				for _, u := range opts.brokers.order() {
+					brokers = append(brokers, u.String())
+				}
+
+				if !slices.Equal(brokers, tt.brokers) {
+					t.Errorf("brokers %v, want %v", brokers, tt.brokers)
+				}
+
+				if opts.clientId != tt.clientId {
+					t.Errorf("client id %q, want %q", opts.clientId, tt.clientId)
+				}
+
+				if opts.version != tt.version {
+					t.Errorf("protocol version %v, want %v", opts.version, tt.version)
+				}
+
+				if (opts.tlsConfig != nil) != tt.tls {
+					t.Errorf("tls %v, want %v", opts.tlsConfig != nil, tt.tls)
+				}
+			},
+		)
+	}
+}
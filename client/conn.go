@@ -0,0 +1,98 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"sync"
+)
+
+// conn is the session of a client, which can be replaced by a session with new options while in use.
+type conn struct {
+	clientId  string
+	transport string
+
+	// reconnectMu serializes reconnections, while mu guards the current session and its options
+	reconnectMu sync.Mutex
+	mu          sync.RWMutex
+	s           session
+	opts        *connOptions
+}
+
+func newConn(opts *connOptions, clientId, transport string) *conn {
+	return &conn{clientId: clientId, transport: transport, s: newSession(opts), opts: opts}
+}
+
+func (c *conn) current() session {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.s
+}
+
+func (c *conn) ProtocolVersion() ProtocolVersion {
+	return c.current().ProtocolVersion()
+}
+
+func (c *conn) Broker() string {
+	return c.current().Broker()
+}
+
+func (c *conn) Connect() error {
+	return c.current().Connect()
+}
+
+func (c *conn) Disconnect(quiesce uint) {
+	c.current().Disconnect(quiesce)
+}
+
+func (c *conn) IsConnected() bool {
+	return c.current().IsConnected()
+}
+
+func (c *conn) Publish(msg *Message) (*PublishResult, error) {
+	return c.current().Publish(msg)
+}
+
+func (c *conn) Subscribe(topic string, qos byte, handler MessageHandler) (*SubscribeResult, error) {
+	return c.current().Subscribe(topic, qos, handler)
+}
+
+// reconnect replaces the session with one of the options, keeping the will and callbacks of the client. If the new
+// session fails to connect, the previous session is connected again and the error is returned.
+func (c *conn) reconnect(o Options) error {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+
+	c.mu.RLock()
+	prev, prevOpts := c.s, c.opts
+	c.mu.RUnlock()
+
+	opts, err := newConnOptions(o, c.clientId, c.transport)
+	if err != nil {
+		return err
+	}
+
+	opts.will = prevOpts.will
+	opts.onConnect = prevOpts.onConnect
+	opts.defaultHandler = prevOpts.defaultHandler
+
+	next := newSession(opts)
+
+	prev.Disconnect(DefaultQuiesceTimeout)
+
+	if err := next.Connect(); err != nil {
+		if err := prev.Connect(); err != nil {
+			prevOpts.log.Error("failed to reconnect with the previous options", "error", err)
+		}
+
+		return err
+	}
+
+	c.mu.Lock()
+	c.s, c.opts = next, opts
+	c.mu.Unlock()
+
+	return nil
+}
@@ -5,13 +5,13 @@
 package client
 
 import (
-	"fmt"
-	"net/http"
-	"sort"
+	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
-	glog "github.com/labstack/gommon/log"
+	"go-mqtt-demo/history"
+	"go-mqtt-demo/logger"
 )
 
 type WebSocketEvent struct {
@@ -20,107 +20,173 @@ type WebSocketEvent struct {
 	Action string
 }
 
+// SseEvent adds or removes an SSE client. Ready is closed once the client receives live messages, after which the
+// client can replay the history without missing any message.
 type SseEvent struct {
-	Writer http.ResponseWriter
-	Done   chan bool
+	Id       int64
+	Messages chan SseMessage
+	Ready    chan struct{}
+	Action   string
 }
 
+// SseMessage is relayed to the SSE clients. Id is 0 if the message is not in the history.
 type SseMessage struct {
-	Data []byte
-}
-
-type OfflineMessage struct {
 	Id      int64
-	Payload []byte
+	Offline bool
+	Data    []byte
 }
 
 type ConnEventWatcher struct {
 	WsEvent       chan WebSocketEvent
 	WsConnections sync.Map
-	OnlineMessage chan []byte
+	OnlineMessage chan *Message
 
 	SseEvent       chan SseEvent
-	SseMessages    sync.Map
-	OfflineMessage chan OfflineMessage
+	SseClients     sync.Map
+	OfflineMessage chan *Message
+
+	// History keeps every received message. Messages after startSeq were received by this process.
+	History  history.Store
+	startSeq int64
+
+	log *slog.Logger
 
 	done chan struct{}
 }
 
-func NewConnEventWatcher() *ConnEventWatcher {
+func NewConnEventWatcher(store history.Store) (*ConnEventWatcher, error) {
+	startSeq, err := store.LastSeq()
+	if err != nil {
+		return nil, err
+	}
+
 	w := &ConnEventWatcher{
 		WsConnections: sync.Map{},
 		WsEvent:       make(chan WebSocketEvent, DefaultBufferSize),
-		OnlineMessage: make(chan []byte, DefaultBufferSize),
+		OnlineMessage: make(chan *Message, DefaultBufferSize),
 
-		SseMessages:    sync.Map{},
+		SseClients:     sync.Map{},
 		SseEvent:       make(chan SseEvent, DefaultBufferSize),
-		OfflineMessage: make(chan OfflineMessage, DefaultBufferSize),
+		OfflineMessage: make(chan *Message, DefaultBufferSize),
+
+		History:  store,
+		startSeq: startSeq,
+
+		log: logger.For(logger.SubsystemWatcher),
 
 		done: make(chan struct{}),
 	}
 
 	go w.run()
 
-	return w
+	return w, nil
 }
 
+// Stop stops relaying the messages. The chans are left open, since messages may still arrive until the connection is
+// closed, e.g. when shared with the publishing client.
 func (w *ConnEventWatcher) Stop() {
-	w.WsConnections.Clear()
-	close(w.WsEvent)
-	close(w.OnlineMessage)
+	close(w.done)
 
-	w.SseMessages.Clear()
-	close(w.SseEvent)
-	close(w.OfflineMessage)
+	w.WsConnections.Clear()
+}
 
-	close(w.done)
+// send passes the message to the chan, unless the watcher is stopped.
+func (w *ConnEventWatcher) send(messages chan *Message, msg *Message) bool {
+	select {
+	case messages <- msg:
+		return true
+	case <-w.done:
+		return false
+	}
 }
 
 func (w *ConnEventWatcher) run() {
 	for {
 		select {
 		case <-w.done:
+			// Closing the messages chans ends the streams of the connected SSE clients
+			w.SseClients.Range(
+				func(k, _ any) bool {
+					w.removeSseClient(k)
+
+					return true
+				},
+			)
+
 			return
 		case event := <-w.WsEvent:
 			switch event.Action {
 			case "add":
 				w.WsConnections.Store(event.Id, event.Conn)
-				glog.Info("websocket connection added")
+				w.log.Info("websocket connection added", "id", event.Id)
 			case "remove":
 				w.WsConnections.Delete(event.Id)
-				glog.Info("websocket connection removed")
+				w.log.Info("websocket connection removed", "id", event.Id)
 			}
 		case msg := <-w.OnlineMessage:
-			w.relayOnlineMessages(msg)
+			if sseMsg, ok := w.record(msg, false); ok {
+				w.relayOnlineMessages(sseMsg.Data)
+				w.relaySseMessage(sseMsg)
+			}
 		case event := <-w.SseEvent:
-			w.replayOfflineMessages(event)
+			switch event.Action {
+			case "add":
+				w.SseClients.Store(event.Id, event.Messages)
+				close(event.Ready)
+				w.log.Info("sse client added", "id", event.Id)
+			case "remove":
+				w.removeSseClient(event.Id)
+			}
 		case msg := <-w.OfflineMessage:
-			w.SseMessages.Store(msg.Id, SseMessage{Data: msg.Payload})
+			if sseMsg, ok := w.record(msg, true); ok {
+				w.relaySseMessage(sseMsg)
+			}
 		}
 	}
 }
 
+// record keeps the message in the history, which assigns its event ID. A message that the history fails to keep is
+// still relayed, without an event ID.
+func (w *ConnEventWatcher) record(msg *Message, offline bool) (SseMessage, bool) {
+	// The topic and MQTT 5 properties are relayed along with the payload
+	data, err := msg.MarshalJSON()
+	if err != nil {
+		w.log.Error("failed to encode message", "topic", msg.Topic, "error", err)
+
+		return SseMessage{}, false
+	}
+
+	rec := &history.Record{Topic: msg.Topic, ReceivedAt: time.Now(), Offline: offline, Data: data}
+	if err := w.History.Append(rec); err != nil {
+		w.log.Error("failed to record message", "topic", msg.Topic, "error", err)
+
+		return SseMessage{Offline: offline, Data: data}, true
+	}
+
+	return SseMessage{Id: rec.Seq, Offline: offline, Data: data}, true
+}
+
 func (w *ConnEventWatcher) relayOnlineMessages(msg []byte) {
 	// WebSocket connections order does not matter, so we can iterate over the map without sorting the connections
 	w.WsConnections.Range(
 		func(k, v any) bool {
 			conn, ok := v.(*websocket.Conn)
 			if !ok {
-				glog.Errorf("invalid data: %v", v)
+				w.log.Error("invalid data", "value", v)
 
 				w.WsConnections.Delete(k)
-				glog.Error("websocket connection removed")
+				w.log.Error("websocket connection removed", "id", k)
 
 				return false
 			}
 
 			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
-				glog.Errorf("failed to send message: %v", err)
+				w.log.Error("failed to send message", "error", err)
 
 				_ = conn.Close()
 
 				w.WsConnections.Delete(k)
-				glog.Error("websocket connection removed")
+				w.log.Error("websocket connection removed", "id", k)
 
 				return false
 			}
@@ -130,55 +196,75 @@ func (w *ConnEventWatcher) relayOnlineMessages(msg []byte) {
 	)
 }
 
-func (w *ConnEventWatcher) replayOfflineMessages(event SseEvent) {
-	iterate := func(k any, r bool) bool {
-		w.SseMessages.Delete(k)
-
-		return r
-	}
-
-	// Offline messages should be written in order, so we'll sort the messages by their ID
-	var keys []int64
-
-	entries := make(map[int64]SseMessage)
-
-	w.SseMessages.Range(
+func (w *ConnEventWatcher) relaySseMessage(msg SseMessage) {
+	w.SseClients.Range(
 		func(k, v any) bool {
-			msg, ok := v.(SseMessage)
+			messages, ok := v.(chan SseMessage)
 			if !ok {
-				glog.Errorf("invalid data: %v", v)
+				w.log.Error("invalid data", "value", v)
+
+				w.SseClients.Delete(k)
 
-				return iterate(k, false)
+				return true
 			}
 
-			key, ok := k.(int64)
-			if !ok {
-				glog.Errorf("invalid key value: %v", k)
+			select {
+			case messages <- msg:
+			default:
+				// A client that cannot keep up is dropped, and resumes from its last event ID when it reconnects
+				w.log.Error("sse client is too slow, dropping it", "id", k)
 
-				return iterate(k, false)
+				w.removeSseClient(k)
 			}
 
-			keys = append(keys, key)
-			entries[key] = msg
-
-			return iterate(k, true)
+			return true
 		},
 	)
+}
 
-	sort.Slice(
-		keys, func(i, j int) bool {
-			return keys[i] < keys[j]
-		},
-	)
+func (w *ConnEventWatcher) removeSseClient(id any) {
+	v, ok := w.SseClients.LoadAndDelete(id)
+	if !ok {
+		return
+	}
+
+	if messages, ok := v.(chan SseMessage); ok {
+		close(messages)
+	}
 
-	// Use the key slice to process in order
-	for _, k := range keys {
-		if _, err := fmt.Fprintf(event.Writer, "data: %s\n\n", entries[k].Data); err != nil {
-			glog.Errorf("failed to write message: %v", err)
+	w.log.Info("sse client removed", "id", id)
+}
+
+// Replay returns the messages after lastEventId from the history. Without a lastEventId, only the messages received
+// while offline since this process started are returned. A lastEventId beyond the latest message is from a history
+// that was since reset, e.g. a memory store before a restart, so every message of the history is returned.
+func (w *ConnEventWatcher) Replay(lastEventId int64) ([]SseMessage, error) {
+	last, err := w.History.LastSeq()
+	if err != nil {
+		return nil, err
+	}
 
-			break
+	since, all := lastEventId, lastEventId != 0
+	if lastEventId > last {
+		since = 0
+	} else if lastEventId == 0 {
+		since = w.startSeq
+	}
+
+	records, err := w.History.Since(since, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []SseMessage
+
+	for _, rec := range records {
+		if !all && !rec.Offline {
+			continue
 		}
+
+		messages = append(messages, SseMessage{Id: rec.Seq, Offline: rec.Offline, Data: rec.Data})
 	}
 
-	event.Done <- true
+	return messages, nil
 }
@@ -0,0 +1,182 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	// FailoverPrimary always tries the brokers in order, so clients return to the first broker once it is back.
+	FailoverPrimary = "primary"
+
+	// FailoverRoundRobin continues with the broker after the one whose connection was lost.
+	FailoverRoundRobin = "round-robin"
+)
+
+// errSkipBroker skips a broker in a round of MQTT 5 connection attempts.
+var errSkipBroker = errors.New("broker skipped by failover policy")
+
+// brokerURLs parses the broker URLs. Without them, it returns the single broker of the address with the scheme, port
+// and path.
+func brokerURLs(list []string, address, scheme, port, path string) ([]*url.URL, error) {
+	if len(list) == 0 {
+		list = []string{fmt.Sprintf("%v://%v:%v%v", scheme, address, port, path)}
+	}
+
+	var urls []*url.URL
+
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+
+		urls = append(urls, u)
+	}
+
+	if len(urls) == 0 {
+		return nil, errors.New("no broker urls")
+	}
+
+	return urls, nil
+}
+
+func hasSecureScheme(urls []*url.URL) bool {
+	for _, u := range urls {
+		if isSecureScheme(u.Scheme) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// brokerSet tracks the broker of the current connection, and orders the connection attempts by the failover policy.
+type brokerSet struct {
+	urls   []*url.URL
+	policy string
+
+	mu sync.Mutex
+
+	// attempting is the index of the broker of the latest connection attempt
+	attempting int
+
+	// active is the index of the connected broker, or -1
+	active int
+
+	// lost is the index of the broker whose connection was lost last, or -1
+	lost int
+}
+
+func newBrokerSet(urls []*url.URL, policy string) *brokerSet {
+	return &brokerSet{urls: urls, policy: policy, active: -1, lost: -1}
+}
+
+func (b *brokerSet) indexOf(u *url.URL) int {
+	for i := range b.urls {
+		if b.urls[i].String() == u.String() {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// attempt records the broker of a connection attempt.
+func (b *brokerSet) attempt(u *url.URL) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if i := b.indexOf(u); i >= 0 {
+		b.attempting = i
+	}
+}
+
+// connected marks the broker of the latest attempt as active, and returns it.
+func (b *brokerSet) connected() *url.URL {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.active = b.attempting
+	b.lost = -1
+
+	return b.urls[b.active]
+}
+
+// disconnected marks the active broker as lost.
+func (b *brokerSet) disconnected() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.active >= 0 {
+		b.lost = b.active
+	}
+
+	b.active = -1
+}
+
+// Active returns the URL of the connected broker, or an empty string.
+func (b *brokerSet) Active() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.active < 0 {
+		return ""
+	}
+
+	return b.urls[b.active].Redacted()
+}
+
+// next returns the index of the first broker of the next attempts.
+func (b *brokerSet) next() int {
+	if b.policy != FailoverRoundRobin || b.lost < 0 {
+		return 0
+	}
+
+	return (b.lost + 1) % len(b.urls)
+}
+
+// order returns the brokers in the order of the next attempts.
+func (b *brokerSet) order() []*url.URL {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	start := b.next()
+
+	servers := make([]*url.URL, 0, len(b.urls))
+	for i := range b.urls {
+		servers = append(servers, b.urls[(start+i)%len(b.urls)])
+	}
+
+	return servers
+}
+
+// skip reports whether to skip the broker in the current round of attempts, which always start from the first
+// broker with MQTT 5. With round-robin, the brokers before the next one are skipped until the round reaches the last
+// broker.
+func (b *brokerSet) skip(u *url.URL) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.indexOf(u)
+	if i < b.next() {
+		return true
+	}
+
+	if i == len(b.urls)-1 {
+		b.lost = -1
+	}
+
+	return false
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// Message is an MQTT message independent of the protocol version used to send or receive it.
+type Message struct {
+	Topic      string      `json:"topic"`
+	Payload    []byte      `json:"-"`
+	Qos        byte        `json:"qos"`
+	Retained   bool        `json:"retained"`
+	Properties *Properties `json:"properties,omitempty"`
+}
+
+// MarshalJSON embeds JSON payloads as-is and falls back to a string for anything else.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	type alias Message
+
+	var payload any = string(m.Payload)
+	if json.Valid(m.Payload) {
+		payload = json.RawMessage(m.Payload)
+	}
+
+	return json.Marshal(
+		struct {
+			*alias
+			Payload any `json:"payload"`
+		}{
+			alias:   (*alias)(m),
+			Payload: payload,
+		},
+	)
+}
+
+type MessageHandler func(msg *Message)
+
+type UserProperty struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Properties holds the MQTT 5 publish properties. They are not available with MQTT 3.1.1.
+type Properties struct {
+	UserProperties  []UserProperty `json:"userProperties,omitempty"`
+	MessageExpiry   *uint32        `json:"messageExpiry,omitempty"`
+	ContentType     string         `json:"contentType,omitempty"`
+	ResponseTopic   string         `json:"responseTopic,omitempty"`
+	CorrelationData []byte         `json:"correlationData,omitempty"`
+}
+
+func (p *Properties) IsEmpty() bool {
+	return p == nil ||
+		len(p.UserProperties) == 0 && p.MessageExpiry == nil && p.ContentType == "" && p.ResponseTopic == "" &&
+			len(p.CorrelationData) == 0
+}
+
+// UserProperty returns the value of the first user property with the given key.
+func (p *Properties) UserProperty(key string) string {
+	if p == nil {
+		return ""
+	}
+
+	for _, u := range p.UserProperties {
+		if u.Key == key {
+			return u.Value
+		}
+	}
+
+	return ""
+}
+
+func (p *Properties) toPaho() *paho.PublishProperties {
+	if p.IsEmpty() {
+		return nil
+	}
+
+	props := &paho.PublishProperties{
+		MessageExpiry:   p.MessageExpiry,
+		ContentType:     p.ContentType,
+		ResponseTopic:   p.ResponseTopic,
+		CorrelationData: p.CorrelationData,
+	}
+
+	for _, u := range p.UserProperties {
+		props.User.Add(u.Key, u.Value)
+	}
+
+	return props
+}
+
+func propertiesFromPaho(props *paho.PublishProperties) *Properties {
+	if props == nil {
+		return nil
+	}
+
+	p := &Properties{
+		MessageExpiry:   props.MessageExpiry,
+		ContentType:     props.ContentType,
+		ResponseTopic:   props.ResponseTopic,
+		CorrelationData: props.CorrelationData,
+	}
+
+	for _, u := range props.User {
+		p.UserProperties = append(p.UserProperties, UserProperty{Key: u.Key, Value: u.Value})
+	}
+
+	if p.IsEmpty() {
+		return nil
+	}
+
+	return p
+}
+
+// PublishResult is the broker's response to a publish. ReasonCode is always 0 (success) with MQTT 3.1.1.
+type PublishResult struct {
+	ReasonCode   byte   `json:"reasonCode"`
+	ReasonString string `json:"reasonString,omitempty"`
+}
+
+// SubscribeResult holds the broker's reason code per subscription. With MQTT 3.1.1, the codes are the granted QoS.
+type SubscribeResult struct {
+	ReasonCodes  []byte `json:"reasonCodes"`
+	ReasonString string `json:"reasonString,omitempty"`
+}
+
+// retryable reports whether the broker refused the subscription only because its packet identifier was in use
+// (0x91), which a new attempt with another identifier may avoid.
+func (r *SubscribeResult) retryable() bool {
+	return slices.Contains(r.ReasonCodes, 0x91)
+}
+
+// err returns an error if the broker refused the subscription, with a reason code of 0x80 or above (0x80 is the
+// only failure code of MQTT 3.1.1).
+func (r *SubscribeResult) err(topic string) error {
+	for _, code := range r.ReasonCodes {
+		if code < 0x80 {
+			continue
+		}
+
+		if r.ReasonString != "" {
+			return fmt.Errorf("subscription to %v refused with reason code 0x%02x: %v", topic, code, r.ReasonString)
+		}
+
+		return fmt.Errorf("subscription to %v refused with reason code 0x%02x", topic, code)
+	}
+
+	return nil
+}
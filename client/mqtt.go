@@ -5,47 +5,114 @@
 package client
 
 import (
-	"fmt"
-	"os"
-
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-	glog "github.com/labstack/gommon/log"
+	"encoding/json"
+	"log/slog"
+	"sync"
 )
 
 type Mqtt struct {
-	mqtt.Client
-	done chan struct{}
+	*conn
+	log         *slog.Logger
+	statusTopic string
+	done        chan struct{}
+
+	// births are published on every connection, after the online status
+	mu     sync.Mutex
+	births []*Message
+}
+
+const (
+	StatusOnline  = "online"
+	StatusOffline = "offline"
+)
+
+// Status is the payload of the retained presence message.
+type Status struct {
+	Status string `json:"status"`
 }
 
-func NewMqtt(caName, clientId string) (*Mqtt, error) {
-	opts := mqtt.NewClientOptions().
-		AddBroker(fmt.Sprintf("mqtts://%v:%v", os.Getenv("BROKER_ADDRESS"), os.Getenv("BROKER_PORT"))).
-		SetDefaultPublishHandler(defaultPublishHandler)
+func statusMessage(topic, status string) *Message {
+	payload, _ := json.Marshal(Status{Status: status})
 
-	if _, err := setTLSConfig(opts, caName); err != nil {
+	return &Message{Topic: topic, Payload: payload, Qos: 1, Retained: true}
+}
+
+// NewMqtt creates the publishing client. With a status topic, the client publishes a retained online status on
+// connect, and registers a Last Will that marks it offline when the connection is lost.
+func NewMqtt(o Options, clientId, statusTopic string) (*Mqtt, error) {
+	opts, err := newConnOptions(o, clientId, TransportTCP)
+	if err != nil {
 		return nil, err
 	}
 
-	setAuth(clientId, opts)
-	setCleanSession(opts)
-	setReconnect(opts)
+	m := newMqtt(opts, statusTopic)
+	m.conn = newConn(opts, clientId, TransportTCP)
+
+	return m, nil
+}
+
+// newMqtt sets the status and births of the publishing client on the options, leaving the connection to the caller.
+func newMqtt(opts *connOptions, statusTopic string) *Mqtt {
+	m := &Mqtt{log: opts.log, statusTopic: statusTopic, done: make(chan struct{})}
+
+	opts.defaultHandler = defaultPublishHandler(opts.log)
+
+	if statusTopic != "" {
+		opts.will = statusMessage(statusTopic, StatusOffline)
+	}
+
+	opts.addOnConnect(
+		func(s session) {
+			if statusTopic != "" {
+				if _, err := s.Publish(statusMessage(statusTopic, StatusOnline)); err != nil {
+					opts.log.Error("failed to publish online status", "topic", statusTopic, "error", err)
+				}
+			}
+
+			m.mu.Lock()
+			births := m.births
+			m.mu.Unlock()
 
-	opts.OnConnectAttempt = onConnectAttempt
-	opts.OnReconnecting = onReconnecting
-	opts.OnConnect = onConnect
-	opts.OnConnectionLost = onConnectionLost
+			for _, msg := range births {
+				if _, err := s.Publish(msg); err != nil {
+					opts.log.Error("failed to publish", "topic", msg.Topic, "error", err)
+				}
+			}
+		},
+	)
 
-	return &Mqtt{Client: mqtt.NewClient(opts), done: make(chan struct{})}, nil
+	return m
+}
+
+// PublishOnConnect registers a message to publish on every connection. It should be called before Connect.
+func (m *Mqtt) PublishOnConnect(msg *Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.births = append(m.births, msg)
+}
+
+// Reconnect connects with the options, e.g. after the broker settings are reloaded. The status and births are
+// published again on the new connection. On failure, the client stays with its previous options.
+func (m *Mqtt) Reconnect(o Options) error {
+	return m.conn.reconnect(o)
 }
 
 func (m *Mqtt) Disconnect() {
-	glog.Infof("disconnecting mqtt client...")
+	m.log.Info("disconnecting mqtt client")
 
 	close(m.done)
 
-	if m.Client.IsConnected() {
-		m.Client.Disconnect(DefaultQuiesceTimeout)
+	if m.conn.IsConnected() {
+		// The Last Will is not sent on a graceful disconnection
+		if m.statusTopic != "" {
+			if _, err := m.conn.Publish(statusMessage(m.statusTopic, StatusOffline)); err != nil {
+				m.log.Error("failed to publish offline status", "topic", m.statusTopic, "error", err)
+			}
+		}
+
+		m.conn.Disconnect(DefaultQuiesceTimeout)
 	}
 
-	glog.Infof("mqtt client disconnected")
+	m.log.Info("mqtt client disconnected")
 }
@@ -0,0 +1,53 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"time"
+
+	"go-mqtt-demo/secret"
+)
+
+// Options configure the connections of the clients. They are usually built from the validated config of the service,
+// so several differently configured clients can exist in one process.
+type Options struct {
+	// BrokerAddress, BrokerPort and BrokerWSPort locate the broker without a list of URLs
+	BrokerAddress string
+	BrokerPort    string
+	BrokerWSPort  string
+
+	// BrokerTLS selects the secure schemes when no scheme is set
+	BrokerTLS      bool
+	BrokerScheme   string
+	BrokerWSScheme string
+
+	// BrokerURLs and BrokerWSURLs list the brokers to fail over between, replacing the address and ports
+	BrokerURLs     []string
+	BrokerWSURLs   []string
+	BrokerFailover string
+
+	// CAFile is the CA of the broker. Without it, the system roots are trusted.
+	CAFile      string
+	SystemRoots bool
+
+	// ClientCertFile and ClientKeyFile authenticate the client with mutual TLS
+	ClientCertFile    string
+	ClientKeyFile     string
+	ClientKeyPassword secret.Value
+	TLSMinVersion     string
+	CipherSuites      []string
+
+	// ClientIDSuffix makes the client IDs of the service unique on the broker
+	ClientIDSuffix       string
+	Username             string
+	Password             secret.Value
+	CleanSession         bool
+	MaxReconnectInterval time.Duration
+	ProtocolVersion      ProtocolVersion
+
+	// SharedConnection shares one connection over the transport between the publishing and subscribing clients
+	SharedConnection bool
+	Transport        string
+}
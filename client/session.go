@@ -0,0 +1,88 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type ProtocolVersion uint
+
+const (
+	ProtocolV311 ProtocolVersion = 4
+	ProtocolV5   ProtocolVersion = 5
+)
+
+func (v ProtocolVersion) String() string {
+	if v == ProtocolV5 {
+		return "5"
+	}
+
+	return "3.1.1"
+}
+
+var ErrPropertiesUnsupported = errors.New("message properties require MQTT 5")
+
+// session is the connection to the broker, implemented once per protocol version.
+type session interface {
+	ProtocolVersion() ProtocolVersion
+
+	// Broker returns the URL of the connected broker, or an empty string while disconnected.
+	Broker() string
+	Connect() error
+	Disconnect(quiesce uint)
+	IsConnected() bool
+	Publish(msg *Message) (*PublishResult, error)
+	Subscribe(topic string, qos byte, handler MessageHandler) (*SubscribeResult, error)
+}
+
+type connOptions struct {
+	version              ProtocolVersion
+	brokers              *brokerSet
+	tlsConfig            *tls.Config
+	clientId             string
+	username             string
+	password             *revealer
+	cleanSession         bool
+	maxReconnectInterval time.Duration
+
+	// log carries the client ID of the connection
+	log *slog.Logger
+
+	// will is published by the broker when the connection is lost
+	will *Message
+
+	// onConnect is called on every (re)connection, and may block
+	onConnect func(s session)
+
+	// defaultHandler receives messages that no subscription handler matched
+	defaultHandler MessageHandler
+}
+
+// addOnConnect chains f after the callbacks already registered, so clients can share a session.
+func (o *connOptions) addOnConnect(f func(s session)) {
+	prev := o.onConnect
+	if prev == nil {
+		o.onConnect = f
+
+		return
+	}
+
+	o.onConnect = func(s session) {
+		prev(s)
+		f(s)
+	}
+}
+
+func newSession(opts *connOptions) session {
+	if opts.version == ProtocolV5 {
+		return newSessionV5(opts)
+	}
+
+	return newSessionV3(opts)
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"crypto/tls"
+	"net/url"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// sessionV3 speaks MQTT 3.1.1 through paho.mqtt.golang.
+type sessionV3 struct {
+	mqtt.Client
+	brokers *brokerSet
+}
+
+func newSessionV3(opts *connOptions) session {
+	o := mqtt.NewClientOptions().
+		SetProtocolVersion(uint(ProtocolV311)).
+		SetTLSConfig(opts.tlsConfig).
+		SetClientID(opts.clientId).
+		SetCredentialsProvider(
+			func() (string, string) {
+				return opts.username, opts.password.reveal()
+			},
+		).
+		SetCleanSession(opts.cleanSession).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(opts.maxReconnectInterval)
+
+	for _, u := range opts.brokers.order() {
+		o.AddBroker(u.String())
+	}
+
+	if w := opts.will; w != nil {
+		o.SetBinaryWill(w.Topic, w.Payload, w.Qos, w.Retained)
+	}
+
+	if opts.defaultHandler != nil {
+		o.SetDefaultPublishHandler(
+			func(_ mqtt.Client, msg mqtt.Message) {
+				opts.defaultHandler(messageFromV3(msg))
+			},
+		)
+	}
+
+	s := &sessionV3{brokers: opts.brokers}
+
+	o.OnConnectAttempt = func(broker *url.URL, tlsCfg *tls.Config) *tls.Config {
+		opts.brokers.attempt(broker)
+
+		opts.log.Info("connecting to broker", "broker", broker.Host)
+
+		return tlsCfg
+	}
+	o.OnReconnecting = func(_ mqtt.Client, o *mqtt.ClientOptions) {
+		// The servers are tried in order on every reconnection attempt
+		o.Servers = opts.brokers.order()
+
+		opts.log.Info("reconnecting to broker")
+	}
+	o.OnConnect = func(_ mqtt.Client) {
+		opts.log.Info("connected to broker", "broker", opts.brokers.connected().Redacted())
+
+		if opts.onConnect != nil {
+			opts.onConnect(s)
+		}
+	}
+	o.OnConnectionLost = func(_ mqtt.Client, err error) {
+		opts.brokers.disconnected()
+
+		opts.log.Info("connection to broker lost", "error", err)
+	}
+
+	s.Client = mqtt.NewClient(o)
+
+	return s
+}
+
+func messageFromV3(msg mqtt.Message) *Message {
+	return &Message{
+		Topic:    msg.Topic(),
+		Payload:  msg.Payload(),
+		Qos:      msg.Qos(),
+		Retained: msg.Retained(),
+	}
+}
+
+func (s *sessionV3) ProtocolVersion() ProtocolVersion {
+	return ProtocolV311
+}
+
+func (s *sessionV3) Broker() string {
+	if !s.IsConnected() {
+		return ""
+	}
+
+	return s.brokers.Active()
+}
+
+func (s *sessionV3) Connect() error {
+	if token := s.Client.Connect(); token.Wait() && token.Error() != nil {
+		return token.Error()
+	}
+
+	return nil
+}
+
+func (s *sessionV3) Publish(msg *Message) (*PublishResult, error) {
+	if !msg.Properties.IsEmpty() {
+		return nil, ErrPropertiesUnsupported
+	}
+
+	if token := s.Client.Publish(msg.Topic, msg.Qos, msg.Retained, msg.Payload); token.Wait() && token.Error() != nil {
+		return nil, token.Error()
+	}
+
+	return &PublishResult{}, nil
+}
+
+func (s *sessionV3) Subscribe(topic string, qos byte, handler MessageHandler) (*SubscribeResult, error) {
+	token := s.Client.Subscribe(
+		topic, qos, func(_ mqtt.Client, msg mqtt.Message) {
+			handler(messageFromV3(msg))
+		},
+	)
+
+	if token.Wait() && token.Error() != nil {
+		return nil, token.Error()
+	}
+
+	res := &SubscribeResult{}
+
+	if st, ok := token.(*mqtt.SubscribeToken); ok {
+		res.ReasonCodes = append(res.ReasonCodes, st.Result()[topic])
+	}
+
+	if err := res.err(topic); err != nil {
+		// paho keeps the handler of a refused subscription, which unsubscribing removes
+		s.Client.Unsubscribe(topic).WaitTimeout(DefaultConnectTimeout)
+
+		return res, err
+	}
+
+	return res, nil
+}
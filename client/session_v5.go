@@ -0,0 +1,321 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	"go-mqtt-demo/logger"
+	"go-mqtt-demo/topic"
+)
+
+type route struct {
+	filter  string
+	handler MessageHandler
+}
+
+// sessionV5 speaks MQTT 5 through paho.golang's autopaho connection manager.
+type sessionV5 struct {
+	opts *connOptions
+	cfg  autopaho.ClientConfig
+
+	cm        atomic.Pointer[autopaho.ConnectionManager]
+	cancel    context.CancelFunc
+	connected atomic.Bool
+
+	// routes are kept across reconnections so queued messages of a persistent session reach their handler
+	mu     sync.RWMutex
+	routes []route
+}
+
+func newSessionV5(opts *connOptions) session {
+	s := &sessionV5{opts: opts}
+
+	// The logs of autopaho and paho are at the level of the mqtt subsystem
+	pahoLog := logger.For(logger.SubsystemMQTT).With("client_id", opts.clientId)
+
+	s.cfg = autopaho.ClientConfig{
+		ServerUrls:                    opts.brokers.urls,
+		TlsCfg:                        opts.tlsConfig,
+		KeepAlive:                     30,
+		CleanStartOnInitialConnection: opts.cleanSession,
+		ConnectUsername:               opts.username,
+		ReconnectBackoff:              reconnectBackoff(opts.maxReconnectInterval),
+		Debug:                         logger.Paho{Logger: pahoLog, Level: slog.LevelDebug},
+		Errors:                        logger.Paho{Logger: pahoLog, Level: slog.LevelError},
+		PahoDebug:                     logger.Paho{Logger: pahoLog, Level: slog.LevelDebug},
+		PahoErrors:                    logger.Paho{Logger: pahoLog, Level: slog.LevelError},
+		OnConnectionUp: func(cm *autopaho.ConnectionManager, _ *paho.Connack) {
+			s.cm.Store(cm)
+			s.connected.Store(true)
+			opts.log.Info("connected to broker", "broker", opts.brokers.connected().Redacted())
+
+			// The callback must not block, while the subscriptions made on connect wait for the broker's response
+			if opts.onConnect != nil {
+				go opts.onConnect(s)
+			}
+		},
+		OnConnectError: func(err error) {
+			if errors.Is(err, errSkipBroker) {
+				return
+			}
+
+			opts.log.Info("connection to broker failed", "error", err)
+		},
+		ConnectPacketBuilder: func(cp *paho.Connect, u *url.URL) (*paho.Connect, error) {
+			if opts.brokers.skip(u) {
+				return nil, errSkipBroker
+			}
+
+			opts.brokers.attempt(u)
+			opts.log.Info("connecting to broker", "broker", u.Host)
+
+			// The password is read on every attempt, so a rotated password is used without a restart
+			if password := opts.password.reveal(); password != "" {
+				cp.PasswordFlag = true
+				cp.Password = []byte(password)
+			}
+
+			return cp, nil
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: opts.clientId,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+				func(pr paho.PublishReceived) (bool, error) {
+					s.route(pr.Packet)
+
+					return true, nil
+				},
+			},
+			OnClientError: func(err error) {
+				s.connected.Store(false)
+				opts.brokers.disconnected()
+				opts.log.Info("connection to broker lost", "error", err)
+			},
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				s.connected.Store(false)
+				opts.brokers.disconnected()
+				opts.log.Info("disconnected by broker", "reason_code", d.ReasonCode)
+			},
+		},
+	}
+
+	if !opts.cleanSession {
+		s.cfg.SessionExpiryInterval = DefaultSessionExpiry
+	}
+
+	if w := opts.will; w != nil {
+		s.cfg.WillMessage = &paho.WillMessage{Retain: w.Retained, QoS: w.Qos, Topic: w.Topic, Payload: w.Payload}
+	}
+
+	return s
+}
+
+func reconnectBackoff(maxInterval time.Duration) autopaho.Backoff {
+	if maxInterval <= time.Second {
+		return autopaho.NewConstantBackoff(time.Second)
+	}
+
+	return autopaho.NewExponentialBackoff(time.Second, maxInterval, time.Second, 2)
+}
+
+func (s *sessionV5) route(p *paho.Publish) {
+	msg := &Message{
+		Topic:      p.Topic,
+		Payload:    p.Payload,
+		Qos:        p.QoS,
+		Retained:   p.Retain,
+		Properties: propertiesFromPaho(p.Properties),
+	}
+
+	// The handlers are called without the lock, so they may subscribe or unsubscribe
+	var handlers []MessageHandler
+
+	s.mu.RLock()
+
+	for _, r := range s.routes {
+		if topic.Match(r.filter, msg.Topic) {
+			handlers = append(handlers, r.handler)
+		}
+	}
+
+	s.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+
+	if len(handlers) == 0 && s.opts.defaultHandler != nil {
+		s.opts.defaultHandler(msg)
+	}
+}
+
+func (s *sessionV5) ProtocolVersion() ProtocolVersion {
+	return ProtocolV5
+}
+
+func (s *sessionV5) Broker() string {
+	if !s.connected.Load() {
+		return ""
+	}
+
+	return s.opts.brokers.Active()
+}
+
+func (s *sessionV5) Connect() error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cm, err := autopaho.NewConnection(ctx, s.cfg)
+	if err != nil {
+		cancel()
+
+		return err
+	}
+
+	awaitCtx, awaitCancel := context.WithTimeout(ctx, DefaultConnectTimeout)
+	defer awaitCancel()
+
+	if err := cm.AwaitConnection(awaitCtx); err != nil {
+		cancel()
+
+		return err
+	}
+
+	s.cm.Store(cm)
+	s.cancel = cancel
+
+	return nil
+}
+
+func (s *sessionV5) Disconnect(quiesce uint) {
+	cm := s.cm.Load()
+	if cm == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(quiesce)*time.Millisecond)
+	defer cancel()
+
+	if err := cm.Disconnect(ctx); err != nil {
+		s.opts.log.Error("failed to disconnect", "error", err)
+	}
+
+	// The connection may have come up while Connect failed, which then never set cancel
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	s.connected.Store(false)
+}
+
+func (s *sessionV5) IsConnected() bool {
+	return s.connected.Load()
+}
+
+func (s *sessionV5) Publish(msg *Message) (*PublishResult, error) {
+	cm := s.cm.Load()
+	if cm == nil {
+		return nil, autopaho.ConnectionDownError
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultConnectTimeout)
+	defer cancel()
+
+	resp, err := cm.Publish(
+		ctx, &paho.Publish{
+			QoS:        msg.Qos,
+			Retain:     msg.Retained,
+			Topic:      msg.Topic,
+			Properties: msg.Properties.toPaho(),
+			Payload:    msg.Payload,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &PublishResult{}
+
+	if resp != nil {
+		res.ReasonCode = resp.ReasonCode
+
+		if resp.Properties != nil {
+			res.ReasonString = resp.Properties.ReasonString
+		}
+	}
+
+	return res, nil
+}
+
+func (s *sessionV5) Subscribe(topic string, qos byte, handler MessageHandler) (*SubscribeResult, error) {
+	s.mu.Lock()
+
+	exists := false
+
+	for i := range s.routes {
+		if s.routes[i].filter == topic {
+			s.routes[i].handler = handler
+			exists = true
+		}
+	}
+
+	if !exists {
+		s.routes = append(s.routes, route{filter: topic, handler: handler})
+	}
+
+	s.mu.Unlock()
+
+	cm := s.cm.Load()
+	if cm == nil {
+		return nil, autopaho.ConnectionDownError
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultConnectTimeout)
+	defer cancel()
+
+	suback, err := cm.Subscribe(
+		ctx, &paho.Subscribe{
+			Subscriptions: []paho.SubscribeOptions{{Topic: topic, QoS: qos}},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SubscribeResult{ReasonCodes: suback.Reasons}
+
+	if suback.Properties != nil {
+		res.ReasonString = suback.Properties.ReasonString
+	}
+
+	if err := res.err(topic); err != nil {
+		s.removeRoute(topic)
+
+		return res, err
+	}
+
+	return res, nil
+}
+
+// removeRoute removes the handler of a refused subscription.
+func (s *sessionV5) removeRoute(filter string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.routes = slices.DeleteFunc(
+		s.routes, func(r route) bool {
+			return r.filter == filter
+		},
+	)
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"fmt"
+
+	"go-mqtt-demo/history"
+)
+
+// NewClients creates the publishing and subscribing clients. With a shared connection, both clients share one
+// connection over the transport of the options, instead of the publishing client connecting over TCP and the
+// subscribing client over WebSocket.
+func NewClients(
+	o Options, pubClientId, subClientId, statusTopic string, topics []string, store history.Store,
+) (*Mqtt, *WebSocket, error) {
+	if !o.SharedConnection {
+		pub, err := NewMqtt(o, pubClientId, statusTopic)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sub, err := NewWebSocket(o, subClientId, topics, store)
+		if err != nil {
+			pub.Disconnect()
+
+			return nil, nil, err
+		}
+
+		return pub, sub, nil
+	}
+
+	transport := o.Transport
+	if transport == "" {
+		transport = TransportTCP
+	}
+
+	if transport != TransportTCP && transport != TransportWebSocket {
+		return nil, nil, fmt.Errorf("unknown transport %q", transport)
+	}
+
+	// The subscriber's client ID is kept, so the subscriptions of a persistent session survive switching modes
+	opts, err := newConnOptions(o, subClientId, transport)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The status is published before subscribing, so the presence is reported as soon as possible
+	pub := newMqtt(opts, statusTopic)
+
+	sub, err := newWebSocket(opts, topics, store)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := newConn(opts, subClientId, transport)
+	pub.conn = c
+	sub.conn = c
+	sub.shared = true
+
+	return pub, sub, nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go-mqtt-demo/logger"
+)
+
+// certReloader loads the client certificate for each TLS handshake, reloading the files once they change. A rotated
+// certificate is then used from the next (re)connection, without a restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	password *revealer
+	log      *slog.Logger
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertReloader(certFile, keyFile string, password *revealer) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile, keyFile: keyFile, password: password, log: logger.For(logger.SubsystemClient),
+	}
+
+	// The certificate is loaded once upfront, so an invalid one fails at startup
+	if _, err := r.GetClientCertificate(nil); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// modified returns the latest modification time of the certificate and key files.
+func (r *certReloader) modified() (time.Time, error) {
+	var latest time.Time
+
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTime, err := r.modified()
+	if err != nil {
+		// The last certificate is kept while the files are being replaced
+		if r.cert != nil {
+			r.log.Error("failed to check client certificate, using the loaded one", "file", r.certFile, "error", err)
+
+			return r.cert, nil
+		}
+
+		return nil, err
+	}
+
+	if r.cert != nil && modTime.Equal(r.modTime) {
+		return r.cert, nil
+	}
+
+	cert, err := loadKeyPair(r.certFile, r.keyFile, []byte(r.password.reveal()))
+	if err != nil {
+		if r.cert != nil {
+			r.log.Error("failed to reload client certificate, using the loaded one", "file", r.certFile, "error", err)
+
+			return r.cert, nil
+		}
+
+		return nil, err
+	}
+
+	if r.cert != nil {
+		r.log.Info("reloaded client certificate", "file", r.certFile)
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+
+	return r.cert, nil
+}
+
+// loadKeyPair loads a certificate and its key. A key encrypted in the legacy PEM format is decrypted with the
+// password.
+func loadKeyPair(certFile, keyFile string, password []byte) (tls.Certificate, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return tls.Certificate{}, fmt.Errorf("no key found in %v", keyFile)
+	}
+
+	// OpenSSL 3 writes encrypted keys as PKCS#8 by default, which crypto/x509 cannot decrypt
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return tls.Certificate{}, fmt.Errorf(
+			"key %v is PKCS#8 encrypted, which is not supported; convert it with `openssl rsa -aes256 -traditional`",
+			keyFile,
+		)
+	}
+
+	//nolint:staticcheck // Legacy PEM encryption is what OpenSSL writes with -traditional, e.g. openssl rsa -aes256
+	if x509.IsEncryptedPEMBlock(block) {
+		if len(password) == 0 {
+			return tls.Certificate{}, fmt.Errorf("key %v is encrypted, but no password is set", keyFile)
+		}
+
+		//nolint:staticcheck // See above
+		der, err := x509.DecryptPEMBlock(block, password)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("failed to decrypt key %v: %w", keyFile, err)
+		}
+
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// parseTLSVersion parses a minimum TLS version such as 1.2. An empty version defaults to TLS 1.2.
+func parseTLSVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls version %q (1.2 or 1.3)", v)
+	}
+}
+
+// parseCipherSuites parses cipher suite names, such as TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. Only the secure suites
+// of crypto/tls are accepted. TLS 1.3 suites are not configurable. Without names, the defaults of crypto/tls are used.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	suites := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		suites[s.Name] = s.ID
+	}
+
+	var ids []uint16
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q", name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return nil, errors.New("no cipher suites set")
+	}
+
+	return ids, nil
+}
@@ -6,91 +6,209 @@ package client
 
 import (
 	"fmt"
-	"os"
+	"log/slog"
+	"slices"
+	"sync"
 	"sync/atomic"
+	"time"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-	glog "github.com/labstack/gommon/log"
+	"go-mqtt-demo/history"
 )
 
 type WebSocket struct {
-	mqtt.Client
+	*conn
 	*ConnEventWatcher
+	log *slog.Logger
+
+	// shared is set when the session belongs to the publishing client, which connects and disconnects it
+	shared bool
+
+	// handlers receive messages in addition to the watcher, by subscription filter
+	mu       sync.Mutex
+	filters  []string
+	handlers map[string][]MessageHandler
+
+	// subscribedAt is when the subscriptions of the first connection were made, in Unix nanoseconds
+	subscribedAt atomic.Int64
 }
 
 // WsQos is the QoS when subscribing. Preferred to use QoS level 1 when subscribing.
 const WsQos = 1
 
-func NewWebSocket(caName, clientId, topic string) (*WebSocket, error) {
-	opts := mqtt.NewClientOptions().
-		AddBroker(fmt.Sprintf("wss://%v:%v/mqtt", os.Getenv("BROKER_ADDRESS"), os.Getenv("BROKER_WS_PORT")))
+// subscribeAttempts bounds the attempts to subscribe to a filter refused with a retryable reason code.
+const subscribeAttempts = 3
 
-	if _, err := setTLSConfig(opts, caName); err != nil {
+// NewWebSocket creates the subscribing client, which relays the messages of the topics to the watcher.
+func NewWebSocket(o Options, clientId string, topics []string, store history.Store) (*WebSocket, error) {
+	opts, err := newConnOptions(o, clientId, TransportWebSocket)
+	if err != nil {
 		return nil, err
 	}
 
-	setAuth(clientId, opts)
-	setCleanSession(opts)
-	setReconnect(opts)
+	ws, err := newWebSocket(opts, topics, store)
+	if err != nil {
+		return nil, err
+	}
+
+	ws.conn = newConn(opts, clientId, TransportWebSocket)
+
+	return ws, nil
+}
+
+// newWebSocket sets the subscriptions of the subscribing client on the options, leaving the connection to the caller.
+func newWebSocket(opts *connOptions, topics []string, store history.Store) (*WebSocket, error) {
+	watcher, err := NewConnEventWatcher(store)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &WebSocket{ConnEventWatcher: watcher, log: opts.log, handlers: make(map[string][]MessageHandler)}
+
+	opts.addOnConnect(
+		func(s session) {
+			var init atomic.Bool
+
+			init.Store(true)
+			relay := func(msg *Message) {
+				ws.relayMessage(msg, init.Load())
+			}
+
+			for _, topic := range topics {
+				ws.subscribe(s, topic, relay)
+			}
+
+			for _, filter := range ws.subscriptions() {
+				if !slices.Contains(topics, filter) {
+					ws.subscribe(s, filter, nil)
+				}
+			}
+
+			init.Store(false) // init is complete and succeeding messages should be sent to the online message chan
+
+			ws.subscribedAt.CompareAndSwap(0, time.Now().UnixNano())
+		},
+	)
+
+	return ws, nil
+}
+
+// Handle registers a handler for the messages matching the filter, which is subscribed to on every connection.
+// It should be called before Connect.
+func (ws *WebSocket) Handle(filter string, handler MessageHandler) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if _, ok := ws.handlers[filter]; !ok {
+		ws.filters = append(ws.filters, filter)
+	}
+
+	ws.handlers[filter] = append(ws.handlers[filter], handler)
+}
+
+func (ws *WebSocket) subscriptions() []string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
 
-	opts.OnConnectAttempt = onConnectAttempt
-	opts.OnReconnecting = onReconnecting
+	return append([]string(nil), ws.filters...)
+}
 
-	watcher := NewConnEventWatcher()
-	opts.OnConnect = func(client mqtt.Client) {
-		var offlineId atomic.Int64
+// subscribe subscribes to the filter once, dispatching each message to the relay (if any) and the handlers.
+func (ws *WebSocket) subscribe(s session, filter string, relay MessageHandler) {
+	handler := func(msg *Message) {
+		if relay != nil {
+			relay(msg)
+		}
 
-		init := true
-		token := client.Subscribe(
-			topic, WsQos, func(_ mqtt.Client, msg mqtt.Message) {
-				relayMessage(watcher, msg, &init, &offlineId)
-			},
-		)
+		ws.mu.Lock()
+		handlers := ws.handlers[filter]
+		ws.mu.Unlock()
 
-		if err := token.Error(); token.Wait() && err != nil {
-			glog.Errorf("subscribe error: %v", err)
+		for _, handler := range handlers {
+			handler(msg)
 		}
+	}
 
-		glog.Infof("connected to broker over websocket")
+	res, err := s.Subscribe(filter, WsQos, handler)
 
-		init = false // init is complete and succeeding messages should be sent to the online message chan
+	// The broker may refuse a packet identifier it still uses for a message in flight to the client
+	for attempt := 1; err != nil && res != nil && res.retryable() && attempt < subscribeAttempts; attempt++ {
+		ws.log.Debug("retrying subscription", "topic", filter, "reason_codes", fmt.Sprint(res.ReasonCodes))
+
+		res, err = s.Subscribe(filter, WsQos, handler)
 	}
 
-	opts.OnConnectionLost = onConnectionLost
+	if err != nil {
+		// A refused subscription has a result with its reason codes
+		if res != nil {
+			ws.log.Error(
+				"failed to subscribe", "topic", filter, "reason_codes", fmt.Sprint(res.ReasonCodes), "error", err,
+			)
+
+			return
+		}
 
-	return &WebSocket{Client: mqtt.NewClient(opts), ConnEventWatcher: watcher}, nil
+		ws.log.Error("failed to subscribe", "topic", filter, "error", err)
+
+		return
+	}
+
+	ws.log.Info("subscribed", "topic", filter, "qos", WsQos, "reason_codes", fmt.Sprint(res.ReasonCodes))
 }
 
-func relayMessage(watcher *ConnEventWatcher, msg mqtt.Message, init *bool, offlineId *atomic.Int64) {
+func (ws *WebSocket) relayMessage(msg *Message, init bool) {
 	// Send messages found upon init to the offline message chan
-	if *init {
-		// MessageID() is always 0 and cannot be used as an ID. Maybe there's a config necessary?
-		offlineId.Add(1)
-
-		watcher.OfflineMessage <- OfflineMessage{
-			Id:      offlineId.Load(),
-			Payload: msg.Payload(),
+	if init {
+		if ws.send(ws.OfflineMessage, msg) {
+			ws.log.Info("received message", append(messageAttrs(msg), "offline", true)...)
 		}
-		glog.Infof(
-			"Message [%v] (while offline) from topic: %v\n>>\t%s", offlineId.Load(), msg.Topic(),
-			msg.Payload(),
-		)
 
 		return
 	}
 
-	glog.Infof("received from topic: %v\n>>\t%s", msg.Topic(), msg.Payload())
-	watcher.OnlineMessage <- msg.Payload()
+	if ws.send(ws.OnlineMessage, msg) {
+		ws.log.Info("received message", messageAttrs(msg)...)
+	}
+}
+
+// SubscribedAt returns when the subscriptions of the first connection were made, or the zero time before. The
+// retained messages of the subscriptions are received right after.
+func (ws *WebSocket) SubscribedAt() time.Time {
+	ns := ws.subscribedAt.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, ns)
+}
+
+// Connect connects to the broker, unless the connection is shared with the publishing client, which connects it.
+func (ws *WebSocket) Connect() error {
+	if ws.shared {
+		return nil
+	}
+
+	return ws.conn.Connect()
+}
+
+// Reconnect connects with the options, and subscribes again. A shared connection is reconnected by the publishing
+// client.
+func (ws *WebSocket) Reconnect(o Options) error {
+	if ws.shared {
+		return nil
+	}
+
+	return ws.conn.reconnect(o)
 }
 
 func (ws *WebSocket) Disconnect() {
-	glog.Infof("disconnecting websocket client...")
+	ws.log.Info("disconnecting websocket client")
 
 	ws.ConnEventWatcher.Stop()
 
-	if ws.Client.IsConnected() {
-		ws.Client.Disconnect(DefaultQuiesceTimeout)
+	// A shared connection is disconnected by the publishing client, after it publishes its offline status
+	if !ws.shared && ws.conn.IsConnected() {
+		ws.conn.Disconnect(DefaultQuiesceTimeout)
 	}
 
-	glog.Infof("websocket client disconnected")
+	ws.log.Info("websocket client disconnected")
 }
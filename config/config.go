@@ -7,45 +7,204 @@ package config
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"go-mqtt-demo/client"
+	"go-mqtt-demo/logger"
+	"go-mqtt-demo/secret"
 )
 
 type Config struct {
 	BrokerAddress        string
 	BrokerPort           string
 	BrokerWSPort         string
+	BrokerTLS            bool
+	BrokerScheme         string
+	BrokerWSScheme       string
+	BrokerCAFile         string
+	BrokerSystemRoots    bool
+	BrokerClientCertFile string
+	BrokerClientKeyFile  string
+	BrokerClientKeyPass  secret.Value
+	BrokerTLSMinVersion  string
+	BrokerCipherSuites   []string
+	BrokerURLs           []string
+	BrokerWSURLs         []string
+	BrokerFailover       string
 	ClientIDSuffix       string
 	MQTTUsername         string
-	MQTTPassword         string
+	MQTTPassword         secret.Value
 	MQTTCleanSession     bool
+	MQTTProtocolVersion  string
+	MQTTSharedConnection bool
+	MQTTTransport        string
 	MaxReconnectInterval time.Duration
 	ServicePort          string
 	LocationId           string
 	KioskId              string
+	KioskGroups          []string
+	HistoryStore         string
+	HistoryPath          string
+	HistoryMaxCount      int
+	HistoryMaxAge        time.Duration
+	ConfigStore          string
+	ConfigStorePath      string
+	KioskConfigPath      string
+	EmbeddedBroker       bool
+	SecretsVaultFile     string
+	SecretsVaultPassword secret.Value
+	LogFormat            string
+	Debug                bool
+	LogLevels            map[string]slog.Level
+	LogFile              logger.FileOptions
+	LogPayload           logger.PayloadOptions
+	AdminToken           secret.Value
+
+	// PrintConfig is set by --print-config
+	PrintConfig bool
+
+	values values
+
+	// vault resolves the secrets of the vault, and is registered once the config is applied
+	vault *secret.Vault
+
+	// args and files are kept to load the config again on reload
+	args  []string
+	files []string
+}
+
+const (
+	DefaultHistoryMaxCount = 10000
+	DefaultHistoryMaxAge   = 7 * 24 * time.Hour
+)
+
+// New loads the config from its sources, see load, and validates it. The args are the command-line arguments without
+// the program name. With --print-config, the config is returned without validation, for the caller to print.
+func New(args []string) (*Config, error) {
+	cfg, err := newConfig(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.PrintConfig {
+		cfg.registerVault()
+	}
+
+	return cfg, nil
 }
 
-func New() (*Config, error) {
-	maxReconnectInterval, err := time.ParseDuration(os.Getenv("MQTT_MAX_RECONNECT_INTERVAL"))
+// newConfig loads and validates the config like New, without registering its vault.
+func newConfig(args []string) (*Config, error) {
+	v, opts, err := load(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Instances sharing a working directory need their own database file
+	suffix := v.get("CLIENT_ID_SUFFIX")
+	v.setDefault("HISTORY_PATH", "history_"+suffix+".db")
+	v.setDefault("CONFIG_STORE_PATH", "configs_"+suffix+".db")
+	v.setDefault("KIOSK_CONFIG_PATH", "config_"+suffix+".json")
+
+	maxReconnectInterval, err := time.ParseDuration(v.get("MQTT_MAX_RECONNECT_INTERVAL"))
 	if err != nil {
 		return nil, fmt.Errorf("invalid max reconnect interval: %w", err)
 	}
 
+	historyMaxCount, err := strconv.Atoi(v.get("HISTORY_MAX_COUNT"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid history max count: %w", err)
+	}
+
+	historyMaxAge, err := time.ParseDuration(v.get("HISTORY_MAX_AGE"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid history max age: %w", err)
+	}
+
+	logLevels, err := logger.ParseLevels(v.get("LOG_LEVELS"))
+	if err != nil {
+		return nil, err
+	}
+
+	logFile, err := parseLogFile(v)
+	if err != nil {
+		return nil, err
+	}
+
+	payloadMaxBytes, err := strconv.Atoi(v.get("LOG_PAYLOAD_MAX_BYTES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload log max bytes: %w", err)
+	}
+
 	cfg := &Config{
-		BrokerAddress:        os.Getenv("BROKER_ADDRESS"),
-		BrokerPort:           os.Getenv("BROKER_PORT"),
-		BrokerWSPort:         os.Getenv("BROKER_WS_PORT"),
-		ClientIDSuffix:       os.Getenv("CLIENT_ID_SUFFIX"),
-		MQTTUsername:         os.Getenv("MQTT_USERNAME"),
-		MQTTPassword:         os.Getenv("MQTT_PASSWORD"),
-		MQTTCleanSession:     os.Getenv("MQTT_CLEAN_SESSION") == "1",
+		BrokerAddress:        v.get("BROKER_ADDRESS"),
+		BrokerPort:           v.get("BROKER_PORT"),
+		BrokerWSPort:         v.get("BROKER_WS_PORT"),
+		BrokerTLS:            v.get("BROKER_TLS") != "0",
+		BrokerScheme:         v.get("BROKER_SCHEME"),
+		BrokerWSScheme:       v.get("BROKER_WS_SCHEME"),
+		BrokerCAFile:         v.get("BROKER_CA_FILE"),
+		BrokerSystemRoots:    v.get("BROKER_SYSTEM_ROOTS") == "1",
+		BrokerClientCertFile: v.get("BROKER_CLIENT_CERT_FILE"),
+		BrokerClientKeyFile:  v.get("BROKER_CLIENT_KEY_FILE"),
+		BrokerClientKeyPass:  v.secret("BROKER_CLIENT_KEY_PASSWORD"),
+		BrokerTLSMinVersion:  v.get("BROKER_TLS_MIN_VERSION"),
+		BrokerCipherSuites:   splitList(v.get("BROKER_TLS_CIPHER_SUITES")),
+		BrokerURLs:           splitList(v.get("BROKER_URLS")),
+		BrokerWSURLs:         splitList(v.get("BROKER_WS_URLS")),
+		BrokerFailover:       v.get("BROKER_FAILOVER"),
+		ClientIDSuffix:       v.get("CLIENT_ID_SUFFIX"),
+		MQTTUsername:         v.get("MQTT_USERNAME"),
+		MQTTPassword:         v.secret("MQTT_PASSWORD"),
+		MQTTCleanSession:     v.get("MQTT_CLEAN_SESSION") == "1",
+		MQTTProtocolVersion:  v.get("MQTT_PROTOCOL_VERSION"),
+		MQTTSharedConnection: v.get("MQTT_SHARED_CONNECTION") == "1",
+		MQTTTransport:        v.get("MQTT_TRANSPORT"),
 		MaxReconnectInterval: maxReconnectInterval,
-		ServicePort:          os.Getenv("SERVICE_PORT"),
-		LocationId:           os.Getenv("LOCATION_ID"),
-		KioskId:              os.Getenv("KIOSK_ID"),
+		ServicePort:          v.get("SERVICE_PORT"),
+		LocationId:           v.get("LOCATION_ID"),
+		KioskId:              v.get("KIOSK_ID"),
+		KioskGroups:          splitList(v.get("KIOSK_GROUPS")),
+		HistoryStore:         v.get("HISTORY_STORE"),
+		HistoryPath:          v.get("HISTORY_PATH"),
+		HistoryMaxCount:      historyMaxCount,
+		HistoryMaxAge:        historyMaxAge,
+		ConfigStore:          v.get("CONFIG_STORE"),
+		ConfigStorePath:      v.get("CONFIG_STORE_PATH"),
+		KioskConfigPath:      v.get("KIOSK_CONFIG_PATH"),
+		EmbeddedBroker:       v.get("EMBEDDED_BROKER") == "1",
+		SecretsVaultFile:     v.get("SECRETS_VAULT_FILE"),
+		SecretsVaultPassword: v.secret("SECRETS_VAULT_PASSWORD"),
+		LogFormat:            v.get("LOG_FORMAT"),
+		Debug:                v.get("DEBUG") == "1",
+		LogLevels:            logLevels,
+		LogFile:              logFile,
+		LogPayload: logger.PayloadOptions{
+			Mode:     v.get("LOG_PAYLOAD"),
+			MaxBytes: payloadMaxBytes,
+			Redact:   splitList(v.get("LOG_PAYLOAD_REDACT")),
+		},
+		AdminToken:  v.secret("ADMIN_TOKEN"),
+		PrintConfig: opts.printConfig,
+		values:      v,
+		args:        args,
+		files:       opts.files,
+	}
+
+	if cfg.SecretsVaultFile != "" {
+		cfg.vault = secret.NewVault(cfg.SecretsVaultFile, cfg.SecretsVaultPassword)
+	}
+
+	if cfg.PrintConfig {
+		return cfg, nil
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -55,6 +214,104 @@ func New() (*Config, error) {
 	return cfg, nil
 }
 
+// registerVault registers the vault of the config, if any, for its secrets to be resolved.
+func (c *Config) registerVault() {
+	if c.vault != nil {
+		secret.Register(secret.SchemeVault, c.vault)
+	}
+}
+
+// parseLogFile parses the options of the log file.
+func parseLogFile(v values) (logger.FileOptions, error) {
+	maxSize, err := strconv.Atoi(v.get("LOG_FILE_MAX_SIZE"))
+	if err != nil {
+		return logger.FileOptions{}, fmt.Errorf("invalid log file max size: %w", err)
+	}
+
+	rotateInterval, err := time.ParseDuration(v.get("LOG_FILE_ROTATE_INTERVAL"))
+	if err != nil {
+		return logger.FileOptions{}, fmt.Errorf("invalid log file rotate interval: %w", err)
+	}
+
+	maxBackups, err := strconv.Atoi(v.get("LOG_FILE_MAX_BACKUPS"))
+	if err != nil {
+		return logger.FileOptions{}, fmt.Errorf("invalid log file max backups: %w", err)
+	}
+
+	maxAge, err := time.ParseDuration(v.get("LOG_FILE_MAX_AGE"))
+	if err != nil {
+		return logger.FileOptions{}, fmt.Errorf("invalid log file max age: %w", err)
+	}
+
+	return logger.FileOptions{
+		Path:           v.get("LOG_FILE"),
+		MaxSize:        maxSize,
+		RotateInterval: rotateInterval,
+		MaxBackups:     maxBackups,
+		MaxAge:         maxAge,
+		Compress:       v.get("LOG_FILE_COMPRESS") == "1",
+	}, nil
+}
+
+// LogOptions returns the options of the logs, without the attributes of the service.
+func (c *Config) LogOptions() logger.Options {
+	return logger.Options{
+		Format:  c.LogFormat,
+		Debug:   c.Debug,
+		Levels:  c.LogLevels,
+		File:    c.LogFile,
+		Payload: c.LogPayload,
+	}
+}
+
+// ClientOptions returns the options of the broker connections.
+func (c *Config) ClientOptions() client.Options {
+	version := client.ProtocolV311
+	if c.MQTTProtocolVersion == "5" {
+		version = client.ProtocolV5
+	}
+
+	return client.Options{
+		BrokerAddress:        c.BrokerAddress,
+		BrokerPort:           c.BrokerPort,
+		BrokerWSPort:         c.BrokerWSPort,
+		BrokerTLS:            c.BrokerTLS,
+		BrokerScheme:         c.BrokerScheme,
+		BrokerWSScheme:       c.BrokerWSScheme,
+		BrokerURLs:           c.BrokerURLs,
+		BrokerWSURLs:         c.BrokerWSURLs,
+		BrokerFailover:       c.BrokerFailover,
+		CAFile:               c.BrokerCAFile,
+		SystemRoots:          c.BrokerSystemRoots,
+		ClientCertFile:       c.BrokerClientCertFile,
+		ClientKeyFile:        c.BrokerClientKeyFile,
+		ClientKeyPassword:    c.BrokerClientKeyPass,
+		TLSMinVersion:        c.BrokerTLSMinVersion,
+		CipherSuites:         c.BrokerCipherSuites,
+		ClientIDSuffix:       c.ClientIDSuffix,
+		Username:             c.MQTTUsername,
+		Password:             c.MQTTPassword,
+		CleanSession:         c.MQTTCleanSession,
+		MaxReconnectInterval: c.MaxReconnectInterval,
+		ProtocolVersion:      version,
+		SharedConnection:     c.MQTTSharedConnection,
+		Transport:            c.MQTTTransport,
+	}
+}
+
+// splitList splits a comma-separated value, ignoring empty items.
+func splitList(v string) []string {
+	var items []string
+
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
 func (c *Config) Validate() error {
 	if err := c.validateEmpty(); err != nil {
 		return err
@@ -64,19 +321,64 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := c.validateSchemes(); err != nil {
+		return err
+	}
+
+	if err := c.validateBrokerURLs(); err != nil {
+		return err
+	}
+
+	if err := c.validateClientCert(); err != nil {
+		return err
+	}
+
+	if err := c.validateSecrets(); err != nil {
+		return err
+	}
+
+	if err := c.validateProtocolVersion(); err != nil {
+		return err
+	}
+
+	if err := c.validateTransport(); err != nil {
+		return err
+	}
+
+	if err := c.validateHistory(); err != nil {
+		return err
+	}
+
+	if err := c.validateConfigStore(); err != nil {
+		return err
+	}
+
+	if err := c.validateIds(); err != nil {
+		return err
+	}
+
+	if err := c.validateKioskGroups(); err != nil {
+		return err
+	}
+
+	if err := c.validateLog(); err != nil {
+		return err
+	}
+
 	return c.validatePorts()
 }
 
 func (c *Config) validateEmpty() error {
-	if c.BrokerAddress == "" {
+	// The address and ports are only used for the brokers without a list of urls
+	if c.BrokerAddress == "" && (len(c.BrokerURLs) == 0 || len(c.BrokerWSURLs) == 0) {
 		return errors.New("broker address is required")
 	}
 
-	if c.BrokerPort == "" {
+	if c.BrokerPort == "" && len(c.BrokerURLs) == 0 {
 		return errors.New("broker port is required")
 	}
 
-	if c.BrokerWSPort == "" {
+	if c.BrokerWSPort == "" && len(c.BrokerWSURLs) == 0 {
 		return errors.New("broker websocket port is required")
 	}
 
@@ -96,13 +398,248 @@ func (c *Config) validateEmpty() error {
 }
 
 func (c *Config) validateBrokerAddress() error {
-	if net.ParseIP(c.BrokerAddress) == nil && !isValidDomain(c.BrokerAddress) {
+	if c.BrokerAddress == "" {
+		return nil
+	}
+
+	if net.ParseIP(c.BrokerAddress) == nil && c.BrokerAddress != "localhost" && !isValidDomain(c.BrokerAddress) {
 		return errors.New("broker address must be a valid ip address or domain name")
 	}
 
 	return nil
 }
 
+func (c *Config) validateSchemes() error {
+	if c.BrokerScheme != "" && c.BrokerScheme != "tcp" && c.BrokerScheme != "ssl" {
+		return fmt.Errorf("unsupported broker scheme %q (tcp or ssl)", c.BrokerScheme)
+	}
+
+	if c.BrokerWSScheme != "" && c.BrokerWSScheme != "ws" && c.BrokerWSScheme != "wss" {
+		return fmt.Errorf("unsupported broker websocket scheme %q (ws or wss)", c.BrokerWSScheme)
+	}
+
+	// The CA file defaults to emqxsl-ca.crt, which only brokers over TLS require
+	if c.BrokerCAFile != "" && c.usesTLS() {
+		if _, err := os.Stat(c.BrokerCAFile); err != nil {
+			return fmt.Errorf("invalid broker ca file (empty to trust the system roots): %w", err)
+		}
+	}
+
+	return nil
+}
+
+// usesTLS returns whether a broker is connected to over TLS.
+func (c *Config) usesTLS() bool {
+	secure := func(scheme string) bool {
+		return scheme == "ssl" || scheme == "wss" || (scheme == "" && c.BrokerTLS)
+	}
+
+	if len(c.BrokerURLs) == 0 && secure(c.BrokerScheme) {
+		return true
+	}
+
+	if len(c.BrokerWSURLs) == 0 && secure(c.BrokerWSScheme) {
+		return true
+	}
+
+	for _, s := range slices.Concat(c.BrokerURLs, c.BrokerWSURLs) {
+		if u, err := url.Parse(s); err == nil && (u.Scheme == "ssl" || u.Scheme == "wss") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) validateBrokerURLs() error {
+	lists := []struct {
+		name    string
+		urls    []string
+		schemes []string
+	}{
+		{"broker", c.BrokerURLs, []string{"tcp", "ssl"}},
+		{"broker websocket", c.BrokerWSURLs, []string{"ws", "wss"}},
+	}
+
+	for _, l := range lists {
+		for _, s := range l.urls {
+			u, err := url.Parse(s)
+			if err != nil {
+				return fmt.Errorf("invalid %v url %q: %w", l.name, s, err)
+			}
+
+			if !slices.Contains(l.schemes, u.Scheme) || u.Host == "" {
+				return fmt.Errorf("invalid %v url %q (%v://host:port)", l.name, s, strings.Join(l.schemes, " or "))
+			}
+		}
+	}
+
+	switch c.BrokerFailover {
+	case "", "primary", "round-robin":
+		return nil
+	default:
+		return fmt.Errorf("unsupported broker failover %q (primary or round-robin)", c.BrokerFailover)
+	}
+}
+
+func (c *Config) validateClientCert() error {
+	if (c.BrokerClientCertFile == "") != (c.BrokerClientKeyFile == "") {
+		return errors.New("broker client certificate and key files must be set together")
+	}
+
+	for _, name := range []string{c.BrokerClientCertFile, c.BrokerClientKeyFile} {
+		if name == "" {
+			continue
+		}
+
+		if _, err := os.Stat(name); err != nil {
+			return fmt.Errorf("invalid broker client certificate: %w", err)
+		}
+	}
+
+	switch c.BrokerTLSMinVersion {
+	case "", "1.2", "1.3":
+		return nil
+	default:
+		return fmt.Errorf("unsupported broker tls min version %q (1.2 or 1.3)", c.BrokerTLSMinVersion)
+	}
+}
+
+// validateSecrets reads the secrets once, so a missing file or vault entry fails at startup, or rejects a reload.
+func (c *Config) validateSecrets() error {
+	for _, k := range keys {
+		if k.secret && c.values.get(k.name) != "" && c.values.get(k.name+"_FILE") != "" {
+			return fmt.Errorf("%v and %v_FILE must not be set together", k.name, k.name)
+		}
+	}
+
+	if c.SecretsVaultPassword.Scheme() == secret.SchemeVault {
+		return errors.New("vault passphrase must not be stored in the vault")
+	}
+
+	if c.SecretsVaultFile != "" && c.SecretsVaultPassword.IsZero() {
+		return errors.New("vault passphrase is required")
+	}
+
+	// The vault of this config is not registered before it is valid
+	providers := make(map[string]secret.Provider)
+	if c.vault != nil {
+		providers[secret.SchemeVault] = c.vault
+	}
+
+	for _, v := range []secret.Value{c.MQTTPassword, c.BrokerClientKeyPass, c.SecretsVaultPassword, c.AdminToken} {
+		if v.IsZero() {
+			continue
+		}
+
+		if _, err := v.RevealWith(providers); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateProtocolVersion() error {
+	switch c.MQTTProtocolVersion {
+	case "", "3.1.1", "5":
+		return nil
+	default:
+		return fmt.Errorf("unsupported mqtt protocol version %q (3.1.1 or 5)", c.MQTTProtocolVersion)
+	}
+}
+
+func (c *Config) validateTransport() error {
+	switch c.MQTTTransport {
+	case "", "tcp", "ws":
+		return nil
+	default:
+		return fmt.Errorf("unsupported mqtt transport %q (tcp or ws)", c.MQTTTransport)
+	}
+}
+
+func (c *Config) validateLog() error {
+	switch c.LogFormat {
+	case "", logger.FormatLogfmt, logger.FormatJSON:
+	default:
+		return fmt.Errorf("unsupported log format %q (logfmt or json)", c.LogFormat)
+	}
+
+	if c.LogFile.MaxSize <= 0 {
+		return errors.New("log file max size must be positive")
+	}
+
+	if c.LogFile.RotateInterval < 0 {
+		return errors.New("log file rotate interval must not be negative")
+	}
+
+	if c.LogFile.MaxBackups < 0 {
+		return errors.New("log file max backups must not be negative")
+	}
+
+	if c.LogFile.MaxAge < 0 {
+		return errors.New("log file max age must not be negative")
+	}
+
+	return c.LogPayload.Validate()
+}
+
+func (c *Config) validateHistory() error {
+	if c.HistoryStore != "" && c.HistoryStore != "bolt" && c.HistoryStore != "memory" {
+		return fmt.Errorf("unsupported history store %q (bolt or memory)", c.HistoryStore)
+	}
+
+	if c.HistoryMaxCount < 0 {
+		return errors.New("history max count must not be negative")
+	}
+
+	if c.HistoryMaxAge < 0 {
+		return errors.New("history max age must not be negative")
+	}
+
+	return nil
+}
+
+func (c *Config) validateConfigStore() error {
+	if c.ConfigStore != "" && c.ConfigStore != "bolt" && c.ConfigStore != "memory" {
+		return fmt.Errorf("unsupported config store %q (bolt or memory)", c.ConfigStore)
+	}
+
+	return nil
+}
+
+// ValidateKiosk checks the config of a kiosk, in addition to Validate.
+func (c *Config) ValidateKiosk() error {
+	if c.KioskId == "" {
+		return errors.New("kiosk id is required")
+	}
+
+	return nil
+}
+
+// validateIds checks the IDs, which are topic levels.
+func (c *Config) validateIds() error {
+	if strings.ContainsAny(c.LocationId, "/+#") {
+		return fmt.Errorf("location id %q must not contain /, + or #", c.LocationId)
+	}
+
+	if strings.ContainsAny(c.KioskId, "/+#") {
+		return fmt.Errorf("kiosk id %q must not contain /, + or #", c.KioskId)
+	}
+
+	return nil
+}
+
+func (c *Config) validateKioskGroups() error {
+	for _, group := range c.KioskGroups {
+		if strings.ContainsAny(group, "/+#") {
+			return fmt.Errorf("kiosk group %q must not contain /, + or #", group)
+		}
+	}
+
+	return nil
+}
+
 func isValidDomain(domain string) bool {
 	// Regular expression to validate domain name
 	regex := `^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}$`
@@ -135,10 +672,14 @@ func (e *PortError) Unwrap() error {
 }
 
 func (c *Config) validatePorts() error {
-	ports := map[string]string{
-		"broker":    c.BrokerPort,
-		"websocket": c.BrokerWSPort,
-		"service":   c.ServicePort,
+	ports := map[string]string{"service": c.ServicePort}
+
+	if c.BrokerPort != "" || len(c.BrokerURLs) == 0 {
+		ports["broker"] = c.BrokerPort
+	}
+
+	if c.BrokerWSPort != "" || len(c.BrokerWSURLs) == 0 {
+		ports["websocket"] = c.BrokerWSPort
 	}
 
 	for name, port := range ports {
@@ -0,0 +1,116 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// readFile reads the values of a YAML, TOML or JSON config file. Keys are the lowercase names of the environment
+// variables, and may be nested, so `broker: {address: localhost}` sets BROKER_ADDRESS. Lists are joined with commas.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	case ".json":
+		err = json.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q (yaml, toml or json)", ext)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %v: %w", path, err)
+	}
+
+	values := make(map[string]string)
+
+	if err := flatten("", doc, values); err != nil {
+		return nil, fmt.Errorf("invalid config file %v: %w", path, err)
+	}
+
+	for name := range values {
+		if _, ok := findKey(name); !ok {
+			return nil, fmt.Errorf("unknown key %q in config file %v", name, path)
+		}
+	}
+
+	return values, nil
+}
+
+func flatten(prefix string, v any, values map[string]string) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			name := strings.ToUpper(strings.ReplaceAll(k, "-", "_"))
+			if prefix != "" {
+				name = prefix + "_" + name
+			}
+
+			if err := flatten(name, item, values); err != nil {
+				return err
+			}
+		}
+	case []any:
+		items := make([]string, 0, len(v))
+
+		for _, item := range v {
+			s, err := scalar(prefix, item)
+			if err != nil {
+				return err
+			}
+
+			items = append(items, s)
+		}
+
+		values[prefix] = strings.Join(items, ",")
+	case nil:
+	default:
+		s, err := scalar(prefix, v)
+		if err != nil {
+			return err
+		}
+
+		values[prefix] = s
+	}
+
+	return nil
+}
+
+// scalar formats a value like its environment variable, e.g. booleans as 0 or 1.
+func scalar(name string, v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+
+		return "0", nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int, int64, uint64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value of %v: %v", name, v)
+	}
+}
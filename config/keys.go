@@ -0,0 +1,120 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"strconv"
+	"strings"
+
+	"go-mqtt-demo/logger"
+)
+
+// key is a config value, named by its environment variable.
+type key struct {
+	name  string
+	def   string
+	usage string
+
+	// secret values may reference a secret provider, and are redacted when printed
+	secret bool
+
+	// restart is set for values that cannot change when the config is reloaded
+	restart bool
+}
+
+// keys are the config values in the order they are printed.
+var keys = []key{
+	{name: "BROKER_ADDRESS", usage: "broker or server address"},
+	{name: "BROKER_PORT", usage: "broker MQTT port"},
+	{name: "BROKER_WS_PORT", usage: "broker WebSocket port"},
+	{name: "BROKER_TLS", def: "1", usage: "connect over TLS without a scheme, 0 or 1"},
+	{name: "BROKER_SCHEME", usage: "MQTT scheme, tcp or ssl"},
+	{name: "BROKER_WS_SCHEME", usage: "WebSocket scheme, ws or wss"},
+	{name: "BROKER_CA_FILE", def: "emqxsl-ca.crt", usage: "CA certificate of the broker, empty for the system roots"},
+	{name: "BROKER_SYSTEM_ROOTS", usage: "trust the system roots in addition to the CA, 0 or 1"},
+	{name: "BROKER_CLIENT_CERT_FILE", usage: "client certificate for mutual TLS"},
+	{name: "BROKER_CLIENT_KEY_FILE", usage: "key of the client certificate"},
+	{name: "BROKER_CLIENT_KEY_PASSWORD", usage: "password of an encrypted key", secret: true},
+	{name: "BROKER_CLIENT_KEY_PASSWORD_FILE", usage: "file containing the password of an encrypted key"},
+	{name: "BROKER_TLS_MIN_VERSION", def: "1.2", usage: "minimum TLS version, 1.2 or 1.3"},
+	{name: "BROKER_TLS_CIPHER_SUITES", usage: "comma-separated TLS 1.2 cipher suites"},
+	{name: "BROKER_URLS", usage: "comma-separated MQTT broker URLs"},
+	{name: "BROKER_WS_URLS", usage: "comma-separated WebSocket broker URLs"},
+	{name: "BROKER_FAILOVER", def: "primary", usage: "failover policy, primary or round-robin"},
+	{name: "CLIENT_ID_SUFFIX", usage: "unique client ID suffix", restart: true},
+	{name: "MQTT_USERNAME", usage: "username of the broker"},
+	{name: "MQTT_PASSWORD", usage: "password of the broker", secret: true},
+	{name: "MQTT_PASSWORD_FILE", usage: "file containing the password of the broker"},
+	{name: "MQTT_CLEAN_SESSION", usage: "start a clean session, 0 or 1"},
+	{name: "MQTT_MAX_RECONNECT_INTERVAL", def: "10s", usage: "maximum interval between reconnection attempts"},
+	{name: "MQTT_PROTOCOL_VERSION", def: "3.1.1", usage: "MQTT version, 3.1.1 or 5", restart: true},
+	{name: "MQTT_SHARED_CONNECTION", usage: "share one connection to publish and subscribe, 0 or 1", restart: true},
+	{name: "MQTT_TRANSPORT", def: "tcp", usage: "transport of a shared connection, tcp or ws", restart: true},
+	{name: "SERVICE_PORT", usage: "port which the service will be bound to", restart: true},
+	{name: "LOCATION_ID", usage: "location identifier", restart: true},
+	{name: "KIOSK_ID", usage: "kiosk identifier", restart: true},
+	{name: "KIOSK_GROUPS", usage: "comma-separated kiosk config groups", restart: true},
+	{name: "HISTORY_STORE", def: "bolt", usage: "history store, bolt or memory", restart: true},
+	{name: "HISTORY_PATH", usage: "history database file", restart: true},
+	{
+		name: "HISTORY_MAX_COUNT", def: strconv.Itoa(DefaultHistoryMaxCount), usage: "messages kept, 0 means no limit",
+		restart: true,
+	},
+	{
+		name: "HISTORY_MAX_AGE", def: DefaultHistoryMaxAge.String(), usage: "age of messages kept, 0 means no limit",
+		restart: true,
+	},
+	{name: "CONFIG_STORE", def: "bolt", usage: "config version store, bolt or memory", restart: true},
+	{name: "CONFIG_STORE_PATH", usage: "config version database file", restart: true},
+	{name: "KIOSK_CONFIG_PATH", usage: "applied config file of the kiosk", restart: true},
+	{name: "EMBEDDED_BROKER", usage: "start the embedded broker, 0 or 1", restart: true},
+	{name: "SECRETS_VAULT_FILE", usage: "vault of the secrets referenced by vault:name", restart: true},
+	{name: "SECRETS_VAULT_PASSWORD", usage: "passphrase of the vault", secret: true, restart: true},
+	{name: "SECRETS_VAULT_PASSWORD_FILE", usage: "file containing the passphrase of the vault", restart: true},
+	{name: "LOG_FORMAT", def: "logfmt", usage: "format of the logs, logfmt or json", restart: true},
+	{name: "DEBUG", usage: "log with debug mode, 0 or 1"},
+	{name: "LOG_FILE", usage: "file that the logs are also written to", restart: true},
+	{
+		name: "LOG_FILE_MAX_SIZE", def: strconv.Itoa(logger.DefaultFileMaxSize),
+		usage: "size in megabytes that rotates the log file", restart: true,
+	},
+	{
+		name: "LOG_FILE_ROTATE_INTERVAL", def: logger.DefaultFileRotateInterval.String(),
+		usage: "interval that rotates the log file, 0 only rotates by size", restart: true,
+	},
+	{
+		name: "LOG_FILE_MAX_BACKUPS", def: strconv.Itoa(logger.DefaultFileMaxBackups),
+		usage: "rotated log files kept, 0 means no limit", restart: true,
+	},
+	{
+		name: "LOG_FILE_MAX_AGE", def: logger.DefaultFileMaxAge.String(),
+		usage: "age of the rotated log files kept, rounded up to days, 0 means no limit", restart: true,
+	},
+	{name: "LOG_FILE_COMPRESS", def: "1", usage: "compress the rotated log files, 0 or 1", restart: true},
+	{name: "LOG_PAYLOAD", def: "truncate", usage: "logging of message payloads, full, truncate, hash or off"},
+	{
+		name: "LOG_PAYLOAD_MAX_BYTES", def: strconv.Itoa(logger.DefaultPayloadMaxBytes),
+		usage: "bytes of a payload logged with truncate",
+	},
+	{name: "LOG_PAYLOAD_REDACT", usage: "comma-separated JSON paths of payload fields to redact, e.g. $.user.password"},
+	{name: "LOG_LEVELS", usage: "comma-separated levels of the log subsystems, e.g. mqtt=debug,http=warn"},
+	{name: "ADMIN_TOKEN", usage: "bearer token of the admin endpoints, which are disabled without it", secret: true},
+	{name: "ADMIN_TOKEN_FILE", usage: "file containing the admin token"},
+}
+
+func findKey(name string) (key, bool) {
+	for _, k := range keys {
+		if k.name == name {
+			return k, true
+		}
+	}
+
+	return key{}, false
+}
+
+// flagName returns the command-line flag of the key, e.g. --broker-address for BROKER_ADDRESS.
+func flagName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
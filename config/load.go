@@ -0,0 +1,186 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/joho/godotenv"
+	"go-mqtt-demo/secret"
+)
+
+// Source is where a config value came from. Each source overrides the ones before it.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceDotenv  Source = "dotenv"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+type value struct {
+	value  string
+	source Source
+}
+
+// values are the resolved config values, by key.
+type values map[string]value
+
+func (v values) get(name string) string {
+	return v[name].value
+}
+
+// secret returns the secret of the key, read from the file of its _FILE key if set, or else parsed from the value,
+// which may reference a secret provider.
+func (v values) secret(name string) secret.Value {
+	if path := v.get(name + "_FILE"); path != "" {
+		return secret.Ref(secret.SchemeFile, path)
+	}
+
+	return secret.Parse(v.get(name))
+}
+
+// set overrides the value. An empty value is set too, so a source can clear the value of an earlier source, e.g.
+// BROKER_CA_FILE= in the environment.
+func (v values) set(name, s string, source Source) {
+	v[name] = value{value: s, source: source}
+}
+
+// setDefault sets a value that derives from others, unless a source set it to a value.
+func (v values) setDefault(name, s string) {
+	if v.get(name) == "" {
+		v.set(name, s, SourceDefault)
+	}
+}
+
+// options are the command-line flags of the loader itself.
+type options struct {
+	printConfig bool
+
+	// files are the config and .env files the values were read from
+	files []string
+}
+
+// load resolves the values from the defaults, then a config file, then the .env file, then the environment, then the
+// command-line flags. A missing .env file is ignored, unless it was named with --env-file.
+func load(args []string) (values, options, error) {
+	set := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+
+	var opts options
+
+	configFile := set.String("config", os.Getenv("CONFIG_FILE"), "config `file` in YAML, TOML or JSON")
+	envFile := set.String("env-file", ".env", "dotenv `file`")
+	set.BoolVar(&opts.printConfig, "print-config", false, "print the config with the source of each value and exit")
+
+	flags := make(map[string]*string, len(keys))
+	for _, k := range keys {
+		flags[k.name] = set.String(flagName(k.name), "", k.usage)
+	}
+
+	if err := set.Parse(args); err != nil {
+		return nil, opts, err
+	}
+
+	v := make(values)
+
+	// Keys without a default are left unset
+	for _, k := range keys {
+		if k.def != "" {
+			v.set(k.name, k.def, SourceDefault)
+		}
+	}
+
+	if *configFile != "" {
+		file, err := readFile(*configFile)
+		if err != nil {
+			return nil, opts, err
+		}
+
+		for name, s := range file {
+			v.set(name, s, SourceFile)
+		}
+
+		opts.files = append(opts.files, *configFile)
+	}
+
+	dotenv, err := godotenv.Read(*envFile)
+	if err != nil && (!errors.Is(err, fs.ErrNotExist) || isFlagSet(set, "env-file")) {
+		return nil, opts, fmt.Errorf("failed to load %v: %w", *envFile, err)
+	}
+
+	opts.files = append(opts.files, *envFile)
+
+	// Only the keys set by a source override the earlier sources, even if set to an empty value
+	for _, k := range keys {
+		if s, ok := dotenv[k.name]; ok {
+			v.set(k.name, s, SourceDotenv)
+		}
+
+		if s, ok := os.LookupEnv(k.name); ok {
+			v.set(k.name, s, SourceEnv)
+		}
+	}
+
+	set.Visit(
+		func(f *flag.Flag) {
+			for _, k := range keys {
+				if flagName(k.name) == f.Name {
+					v.set(k.name, *flags[k.name], SourceFlag)
+				}
+			}
+		},
+	)
+
+	return v, opts, nil
+}
+
+func isFlagSet(set *flag.FlagSet, name string) bool {
+	found := false
+
+	set.Visit(
+		func(f *flag.Flag) {
+			if f.Name == name {
+				found = true
+			}
+		},
+	)
+
+	return found
+}
+
+// Source returns where the value of the key came from, or an empty source if the key is unset.
+func (c *Config) Source(name string) Source {
+	return c.values[name].source
+}
+
+// Print writes the set values in dotenv format, each with its source. Secrets are redacted, while references to
+// secret providers are printed as is.
+func (c *Config) Print(w io.Writer) error {
+	for _, k := range keys {
+		val, ok := c.values[k.name]
+		if !ok {
+			continue
+		}
+
+		s := val.value
+		if k.secret {
+			s = secret.Parse(s).String()
+		}
+
+		if _, err := fmt.Fprintf(w, "%v=%v # %v\n", k.name, s, val.source); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
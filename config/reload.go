@@ -0,0 +1,180 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultReloadInterval is how often the config files are checked for changes.
+const DefaultReloadInterval = 2 * time.Second
+
+// ReloadFunc applies a reloaded config. It returns an error if the config cannot be applied, leaving its state as it
+// was with the previous config. If a later function rejects the config, it is called again with the configs swapped,
+// to restore the previous one.
+type ReloadFunc func(prev, next *Config) error
+
+// Reloader reloads the config on SIGHUP, or when the config file or .env file changes. An invalid config is rejected,
+// and the current config is kept.
+type Reloader struct {
+	mu      sync.Mutex
+	current *Config
+	funcs   []ReloadFunc
+
+	modTimes map[string]time.Time
+	done     chan struct{}
+}
+
+func NewReloader(cfg *Config) *Reloader {
+	r := &Reloader{current: cfg, done: make(chan struct{})}
+	r.modTimes = r.readModTimes()
+
+	return r
+}
+
+// OnReload registers a function to apply a reloaded config. The functions are called in order, and the first error
+// rejects the config, restoring the previous one with the functions already called. Functions that may fail should
+// be registered last. It should be called before Start.
+func (r *Reloader) OnReload(f ReloadFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.funcs = append(r.funcs, f)
+}
+
+// Current returns the config applied last.
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Reload loads the config again from its sources, and applies it if valid. The whole config is validated before any
+// function applies it.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := newConfig(r.current.args)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	if changed := r.current.restartChanges(next); len(changed) > 0 {
+		return fmt.Errorf("%v cannot change without a restart", strings.Join(changed, ", "))
+	}
+
+	// The secrets of the vault are revealed when the functions reconnect
+	next.registerVault()
+
+	for i, f := range r.funcs {
+		if err := f(r.current, next); err != nil {
+			r.restore(r.funcs[:i], next)
+
+			return err
+		}
+	}
+
+	r.current = next
+
+	return nil
+}
+
+// restore registers the vault of the current config again, and applies the current config with the functions that
+// applied the rejected one, in reverse order.
+func (r *Reloader) restore(funcs []ReloadFunc, rejected *Config) {
+	r.current.registerVault()
+
+	for i := len(funcs) - 1; i >= 0; i-- {
+		if err := funcs[i](rejected, r.current); err != nil {
+			slog.Error("failed to restore the current config", "error", err)
+		}
+	}
+}
+
+// restartChanges returns the keys that changed, but only apply on restart.
+func (c *Config) restartChanges(next *Config) []string {
+	var changed []string
+
+	for _, k := range keys {
+		if k.restart && c.values.get(k.name) != next.values.get(k.name) {
+			changed = append(changed, k.name)
+		}
+	}
+
+	return changed
+}
+
+// Start reloads the config on SIGHUP, and when a config file is modified.
+func (r *Reloader) Start() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sig)
+
+		ticker := time.NewTicker(DefaultReloadInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-sig:
+				slog.Info("reloading config on SIGHUP")
+			case <-ticker.C:
+				modTimes := r.readModTimes()
+				if maps.EqualFunc(modTimes, r.modTimes, time.Time.Equal) {
+					continue
+				}
+
+				r.modTimes = modTimes
+
+				slog.Info("reloading config on file change")
+			}
+
+			if err := r.Reload(); err != nil {
+				slog.Error("failed to reload config, keeping the current one", "error", err)
+
+				continue
+			}
+
+			slog.Info("config reloaded")
+		}
+	}()
+}
+
+// readModTimes returns the modification times of the config files. A missing file has the zero time.
+func (r *Reloader) readModTimes() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+
+	for _, name := range r.Current().files {
+		var modTime time.Time
+
+		if fi, err := os.Stat(name); err == nil {
+			modTime = fi.ModTime()
+		}
+
+		modTimes[name] = modTime
+	}
+
+	return modTimes
+}
+
+// Close stops reloading the config.
+func (r *Reloader) Close() error {
+	close(r.done)
+
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package configstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var versionsBucket = []byte("versions")
+
+// BoltStore keeps the config versions in a local bbolt database file. Versions are keyed by their big-endian number,
+// and the bucket sequence holds the last version assigned.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func OpenBolt(path string) (*BoltStore, error) {
+	// The timeout prevents blocking forever when another process already holds the file lock
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(
+		func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(versionsBucket)
+
+			return err
+		},
+	)
+	if err != nil {
+		_ = db.Close()
+
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func versionKey(version int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(version))
+
+	return key
+}
+
+func (s *BoltStore) Save(v *Version) error {
+	return s.db.Update(
+		func(tx *bolt.Tx) error {
+			b := tx.Bucket(versionsBucket)
+
+			v.Version = nextVersion(int64(b.Sequence()), v.PublishedAt)
+
+			if err := b.SetSequence(uint64(v.Version)); err != nil {
+				return err
+			}
+
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+
+			return b.Put(versionKey(v.Version), value)
+		},
+	)
+}
+
+func (s *BoltStore) Get(version int64) (*Version, error) {
+	var v *Version
+
+	err := s.db.View(
+		func(tx *bolt.Tx) error {
+			value := tx.Bucket(versionsBucket).Get(versionKey(version))
+			if value == nil {
+				return ErrNotFound
+			}
+
+			v = &Version{}
+
+			return json.Unmarshal(value, v)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (s *BoltStore) List(limit int) ([]Version, error) {
+	versions := []Version{}
+
+	err := s.db.View(
+		func(tx *bolt.Tx) error {
+			c := tx.Bucket(versionsBucket).Cursor()
+
+			for k, value := c.Last(); k != nil; k, value = c.Prev() {
+				if limit > 0 && len(versions) == limit {
+					break
+				}
+
+				var v Version
+				if err := json.Unmarshal(value, &v); err != nil {
+					return err
+				}
+
+				versions = append(versions, v)
+			}
+
+			return nil
+		},
+	)
+
+	return versions, err
+}
+
+func (s *BoltStore) Delete(version int64) error {
+	return s.db.Update(
+		func(tx *bolt.Tx) error {
+			return tx.Bucket(versionsBucket).Delete(versionKey(version))
+		},
+	)
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package configstore
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	OpAdded   = "added"
+	OpRemoved = "removed"
+	OpChanged = "changed"
+)
+
+// Change is a difference between two configs. Path is a JSON pointer (RFC 6901) to the changed value.
+type Change struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+	From any    `json:"from,omitempty"`
+	To   any    `json:"to,omitempty"`
+}
+
+// Diff returns the changes from one config to another, ordered by path. Objects are compared recursively, and other
+// values, including arrays, as a whole.
+func Diff(from, to json.RawMessage) ([]Change, error) {
+	var a, b any
+
+	if err := json.Unmarshal(from, &a); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(to, &b); err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	diff("", a, b, &changes)
+
+	sort.Slice(
+		changes, func(i, j int) bool {
+			return changes[i].Path < changes[j].Path
+		},
+	)
+
+	return changes, nil
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func diff(path string, a, b any, changes *[]Change) {
+	objA, okA := a.(map[string]any)
+	objB, okB := b.(map[string]any)
+
+	if !okA || !okB {
+		if !reflect.DeepEqual(a, b) {
+			*changes = append(*changes, Change{Path: path, Op: OpChanged, From: a, To: b})
+		}
+
+		return
+	}
+
+	for k, va := range objA {
+		p := path + "/" + pointerEscaper.Replace(k)
+
+		if vb, ok := objB[k]; ok {
+			diff(p, va, vb, changes)
+		} else {
+			*changes = append(*changes, Change{Path: p, Op: OpRemoved, From: va})
+		}
+	}
+
+	for k, vb := range objB {
+		if _, ok := objA[k]; !ok {
+			*changes = append(*changes, Change{Path: path + "/" + pointerEscaper.Replace(k), Op: OpAdded, To: vb})
+		}
+	}
+}
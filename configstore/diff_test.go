@@ -0,0 +1,98 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package configstore
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		want    []Change
+		wantErr bool
+	}{
+		{
+			name: "equal",
+			from: `{"display": {"brightness": 80}, "langs": ["en", "fr"]}`,
+			to:   `{"display": {"brightness": 80}, "langs": ["en", "fr"]}`,
+			want: []Change{},
+		},
+		{
+			name: "nested added, removed and changed",
+			from: `{"display": {"brightness": 80, "theme": "light"}, "volume": 10}`,
+			to:   `{"display": {"brightness": 60, "timeout": 30}, "volume": 10}`,
+			want: []Change{
+				{Path: "/display/brightness", Op: OpChanged, From: 80.0, To: 60.0},
+				{Path: "/display/theme", Op: OpRemoved, From: "light"},
+				{Path: "/display/timeout", Op: OpAdded, To: 30.0},
+			},
+		},
+		{
+			name: "object added",
+			from: `{}`,
+			to:   `{"display": {"brightness": 80}}`,
+			want: []Change{{Path: "/display", Op: OpAdded, To: map[string]any{"brightness": 80.0}}},
+		},
+		{
+			name: "object replaced by a value",
+			from: `{"display": {"brightness": 80}}`,
+			to:   `{"display": "off"}`,
+			want: []Change{{Path: "/display", Op: OpChanged, From: map[string]any{"brightness": 80.0}, To: "off"}},
+		},
+		{
+			name: "arrays compared as a whole",
+			from: `{"langs": ["en", "fr"]}`,
+			to:   `{"langs": ["en", "de"]}`,
+			want: []Change{{Path: "/langs", Op: OpChanged, From: []any{"en", "fr"}, To: []any{"en", "de"}}},
+		},
+		{
+			name: "keys escaped in the path",
+			from: `{"a/b": 1, "c~d": 1}`,
+			to:   `{"a/b": 2, "c~d": 2}`,
+			want: []Change{
+				{Path: "/a~1b", Op: OpChanged, From: 1.0, To: 2.0},
+				{Path: "/c~0d", Op: OpChanged, From: 1.0, To: 2.0},
+			},
+		},
+		{
+			name: "empty documents",
+			from: `{}`,
+			to:   `{}`,
+			want: []Change{},
+		},
+		{
+			name: "null document",
+			from: `null`,
+			to:   `{"volume": 10}`,
+			want: []Change{{Path: "", Op: OpChanged, To: map[string]any{"volume": 10.0}}},
+		},
+		{
+			name:    "missing document",
+			from:    ``,
+			to:      `{}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := Diff(json.RawMessage(tt.from), json.RawMessage(tt.to))
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("error %v, want error %v", err, tt.wantErr)
+				}
+
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("changes %+v, want %+v", got, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package configstore
+
+import (
+	"sync"
+)
+
+// MemoryStore keeps the config versions in memory, so they are lost on restart.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	versions []Version
+	last     int64
+}
+
+func NewMemory() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Save(v *Version) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.last = nextVersion(s.last, v.PublishedAt)
+	v.Version = s.last
+
+	s.versions = append(s.versions, *v)
+
+	return nil
+}
+
+func (s *MemoryStore) Get(version int64) (*Version, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := range s.versions {
+		if s.versions[i].Version == version {
+			v := s.versions[i]
+
+			return &v, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) List(limit int) ([]Version, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := make([]Version, 0, len(s.versions))
+
+	for i := len(s.versions) - 1; i >= 0; i-- {
+		if limit > 0 && len(versions) == limit {
+			break
+		}
+
+		versions = append(versions, s.versions[i])
+	}
+
+	return versions, nil
+}
+
+func (s *MemoryStore) Delete(version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.versions {
+		if s.versions[i].Version == version {
+			s.versions = append(s.versions[:i], s.versions[i+1:]...)
+
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package configstore keeps every config version published by admin, so it can be compared and rolled back.
+package configstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-mqtt-demo/client"
+)
+
+var ErrNotFound = errors.New("config version not found")
+
+// Version is a published config. Data is the config without the envelope.
+type Version struct {
+	Version     int64              `json:"version"`
+	Topic       string             `json:"topic"`
+	Author      string             `json:"author,omitempty"`
+	PublishedAt time.Time          `json:"publishedAt"`
+	Data        json.RawMessage    `json:"data"`
+	Properties  *client.Properties `json:"properties,omitempty"`
+
+	// RollbackOf is the earlier version that this version republished
+	RollbackOf int64 `json:"rollbackOf,omitempty"`
+}
+
+// nextVersion returns a version based on the publish time, which is always greater than the last version.
+func nextVersion(last int64, publishedAt time.Time) int64 {
+	return max(last+1, publishedAt.UnixMilli())
+}
+
+// Store keeps the config versions. Versions are assigned by the store, and keep increasing across restarts of a
+// durable store.
+type Store interface {
+	// Save assigns the next version to the config and stores it.
+	Save(v *Version) error
+
+	// Get returns the config version, or ErrNotFound.
+	Get(version int64) (*Version, error)
+
+	// List returns up to limit config versions, latest first. A limit of 0 returns all of them.
+	List(limit int) ([]Version, error)
+
+	// Delete removes a config version. The version is not assigned again.
+	Delete(version int64) error
+
+	Close() error
+}
+
+const (
+	Bolt   = "bolt"
+	Memory = "memory"
+)
+
+// Open creates the store of the given kind. The path is only used by durable stores.
+func Open(kind, path string) (Store, error) {
+	switch kind {
+	case "", Bolt:
+		return OpenBolt(path)
+	case Memory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unsupported config store %q", kind)
+	}
+}
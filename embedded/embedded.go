@@ -0,0 +1,71 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package embedded runs an MQTT broker within the process, for local development and tests without a network.
+package embedded
+
+import (
+	"errors"
+	"log/slog"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+)
+
+// Options sets the listen addresses of the broker, e.g. :1883. An empty address disables the listener.
+type Options struct {
+	TCPAddress string
+	WSAddress  string
+}
+
+// Broker is an MQTT broker that accepts any client, over plain TCP and WebSocket.
+type Broker struct {
+	server *mqtt.Server
+}
+
+// Start starts the listeners of the broker, and returns once they accept connections.
+func Start(opts Options) (*Broker, error) {
+	if opts.TCPAddress == "" && opts.WSAddress == "" {
+		return nil, errors.New("embedded broker requires a tcp or websocket address")
+	}
+
+	// The broker logs through the same pipeline as the service
+	server := mqtt.New(&mqtt.Options{Logger: slog.Default().With("component", "broker")})
+
+	if err := server.AddHook(new(auth.AllowHook), nil); err != nil {
+		return nil, err
+	}
+
+	if opts.TCPAddress != "" {
+		if err := server.AddListener(listeners.NewTCP(listeners.Config{ID: "tcp", Address: opts.TCPAddress})); err != nil {
+			_ = server.Close()
+
+			return nil, err
+		}
+	}
+
+	// The listener serves every path, including /mqtt of the clients
+	if opts.WSAddress != "" {
+		if err := server.AddListener(listeners.NewWebsocket(listeners.Config{ID: "ws", Address: opts.WSAddress})); err != nil {
+			_ = server.Close()
+
+			return nil, err
+		}
+	}
+
+	if err := server.Serve(); err != nil {
+		_ = server.Close()
+
+		return nil, err
+	}
+
+	slog.Info("embedded broker listening", "tcp", opts.TCPAddress, "ws", opts.WSAddress)
+
+	return &Broker{server: server}, nil
+}
+
+func (b *Broker) Close() error {
+	return b.server.Close()
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package embedded_test
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"go-mqtt-demo/client"
+	"go-mqtt-demo/embedded"
+	"go-mqtt-demo/history"
+)
+
+// freePort returns a port that is free on the machine, for the broker to listen on.
+func freePort(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+}
+
+func TestPublishAndReceive(t *testing.T) {
+	tests := []struct {
+		name    string
+		version client.ProtocolVersion
+		shared  bool
+	}{
+		{"mqtt 3.1.1", client.ProtocolV311, false},
+		{"mqtt 5", client.ProtocolV5, false},
+		{"shared connection", client.ProtocolV5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				port, wsPort := freePort(t), freePort(t)
+
+				b, err := embedded.Start(
+					embedded.Options{TCPAddress: "localhost:" + port, WSAddress: "localhost:" + wsPort},
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				defer b.Close()
+
+				o := client.Options{
+					BrokerAddress:        "localhost",
+					BrokerPort:           port,
+					BrokerWSPort:         wsPort,
+					ClientIDSuffix:       "test",
+					CleanSession:         true,
+					MaxReconnectInterval: time.Second,
+					ProtocolVersion:      tt.version,
+					SharedConnection:     tt.shared,
+				}
+
+				store := history.NewMemory(history.Retention{})
+				topics := []string{"test/#"}
+
+				pub, sub, err := client.NewClients(o, "pub_client", "sub_client", "test/status", topics, store)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				received := make(chan *client.Message, 1)
+
+				sub.Handle(
+					"test/sensor", func(msg *client.Message) {
+						received <- msg
+					},
+				)
+
+				if err := pub.Connect(); err != nil {
+					t.Fatal(err)
+				}
+
+				defer pub.Disconnect()
+
+				if err := sub.Connect(); err != nil {
+					t.Fatal(err)
+				}
+
+				defer sub.Disconnect()
+
+				// The message is retained, so it is received even if published before the subscription
+				msg := &client.Message{Topic: "test/sensor", Payload: []byte(`{"t":1}`), Qos: 1, Retained: true}
+				if _, err := pub.Publish(msg); err != nil {
+					t.Fatal(err)
+				}
+
+				select {
+				case got := <-received:
+					if string(got.Payload) != string(msg.Payload) {
+						t.Errorf("received payload %s, want %s", got.Payload, msg.Payload)
+					}
+				case <-time.After(10 * time.Second):
+					t.Fatal("message not received")
+				}
+			},
+		)
+	}
+}
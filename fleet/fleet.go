@@ -0,0 +1,214 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package fleet
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"go-mqtt-demo/client"
+	"go-mqtt-demo/kioskconfig"
+	"go-mqtt-demo/logger"
+	"go-mqtt-demo/presence"
+	"go-mqtt-demo/topic"
+)
+
+// log logs at the level of the handler subsystem.
+var log = logger.For(logger.SubsystemHandler)
+
+// Announcement is published retained by a kiosk on every connection.
+type Announcement struct {
+	Version       string   `json:"version"`
+	ConfigVersion int64    `json:"configVersion,omitempty"`
+	Groups        []string `json:"groups,omitempty"`
+}
+
+const (
+	StateUnknown = "unknown"
+	StateOnline  = "online"
+	StateOffline = "offline"
+)
+
+type Kiosk struct {
+	Id            string     `json:"id"`
+	LocationId    string     `json:"locationId"`
+	Version       string     `json:"version,omitempty"`
+	ConfigVersion int64      `json:"configVersion,omitempty"`
+	Groups        []string   `json:"groups,omitempty"`
+	State         string     `json:"state"`
+	LastSensorAt  *time.Time `json:"lastSensorAt,omitempty"`
+	DiscoveredAt  time.Time  `json:"discoveredAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// Registry discovers kiosks from the topics of the messages they send, and keeps their metadata. Their state is the
+// presence of the presence table.
+type Registry struct {
+	mu       sync.RWMutex
+	kiosks   map[string]*Kiosk
+	presence *presence.Table
+}
+
+func NewRegistry(presence *presence.Table) *Registry {
+	return &Registry{kiosks: make(map[string]*Kiosk), presence: presence}
+}
+
+// discover returns the kiosk of the topic, adding it to the registry if new. It must be called with mu held.
+func (r *Registry) discover(kioskTopic string, now time.Time) *Kiosk {
+	locationId, kioskId, ok := topic.ParseKiosk(kioskTopic)
+	if !ok {
+		return nil
+	}
+
+	k, ok := r.kiosks[locationId+"/"+kioskId]
+	if !ok {
+		k = &Kiosk{Id: kioskId, LocationId: locationId, DiscoveredAt: now}
+		r.kiosks[locationId+"/"+kioskId] = k
+
+		log.Info("discovered kiosk", "kiosk_id", kioskId)
+	}
+
+	k.UpdatedAt = now
+
+	return k
+}
+
+// HandleAnnounce updates the metadata of the kiosk. A cleared announcement, e.g. by Delete, is ignored.
+func (r *Registry) HandleAnnounce(msg *client.Message) {
+	if len(msg.Payload) == 0 {
+		return
+	}
+
+	var a Announcement
+	if err := json.Unmarshal(msg.Payload, &a); err != nil {
+		log.Error("invalid announcement", "topic", msg.Topic, "error", err)
+
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if k := r.discover(msg.Topic, time.Now()); k != nil {
+		k.Version = a.Version
+		k.ConfigVersion = a.ConfigVersion
+		k.Groups = a.Groups
+	}
+}
+
+func (r *Registry) HandleSensor(msg *client.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+
+	if k := r.discover(msg.Topic, now); k != nil {
+		k.LastSensorAt = &now
+	}
+}
+
+// HandleAck records the config version applied by a kiosk.
+func (r *Registry) HandleAck(msg *client.Message) {
+	var ack kioskconfig.Ack
+	if err := json.Unmarshal(msg.Payload, &ack); err != nil || ack.Status != kioskconfig.AckApplied {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if k := r.discover(msg.Topic, time.Now()); k != nil && ack.Version > k.ConfigVersion {
+		k.ConfigVersion = ack.Version
+	}
+}
+
+// Targets returns the IDs of the known kiosks that receive the config published to the topic.
+func (r *Registry) Targets(configTopic string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []string
+
+	for _, k := range r.kiosks {
+		targeted := configTopic == topic.LocationConfig(k.LocationId) ||
+			configTopic == topic.KioskConfig(k.LocationId, k.Id)
+
+		for _, group := range k.Groups {
+			targeted = targeted || configTopic == topic.GroupConfig(k.LocationId, group)
+		}
+
+		if targeted {
+			ids = append(ids, k.Id)
+		}
+	}
+
+	return ids
+}
+
+// withState returns a copy of the kiosk, with its state from the presence table.
+func (r *Registry) withState(k *Kiosk) Kiosk {
+	kiosk := *k
+	kiosk.State = StateUnknown
+
+	if p, ok := r.presence.Get(k.LocationId, k.Id); ok {
+		kiosk.State = StateOffline
+		if p.Online {
+			kiosk.State = StateOnline
+		}
+	}
+
+	return kiosk
+}
+
+// List returns all kiosks, ordered by location and kiosk ID.
+func (r *Registry) List() []Kiosk {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	kiosks := make([]Kiosk, 0, len(r.kiosks))
+	for _, k := range r.kiosks {
+		kiosks = append(kiosks, r.withState(k))
+	}
+
+	sort.Slice(
+		kiosks, func(i, j int) bool {
+			if kiosks[i].LocationId != kiosks[j].LocationId {
+				return kiosks[i].LocationId < kiosks[j].LocationId
+			}
+
+			return kiosks[i].Id < kiosks[j].Id
+		},
+	)
+
+	return kiosks
+}
+
+func (r *Registry) Get(locationId, id string) (Kiosk, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	k, ok := r.kiosks[locationId+"/"+id]
+	if !ok {
+		return Kiosk{}, false
+	}
+
+	return r.withState(k), true
+}
+
+// Delete removes the kiosk, which is discovered again on its next message.
+func (r *Registry) Delete(locationId, id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.kiosks[locationId+"/"+id]; !ok {
+		return false
+	}
+
+	delete(r.kiosks, locationId+"/"+id)
+
+	return true
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go-mqtt-demo/client"
+	"go-mqtt-demo/fleet"
+	"go-mqtt-demo/topic"
+)
+
+// Fleet handles the kiosk registry endpoints of admin.
+type Fleet struct {
+	h          *Handler
+	registry   *fleet.Registry
+	locationId string
+}
+
+// NewFleet discovers the kiosks of the location from their announcements and sensor messages, with their state from
+// the presence table.
+func NewFleet(h *Handler, p *Presence, locationId, sensorFilter string) *Fleet {
+	registry := fleet.NewRegistry(p.table)
+
+	h.ws.Handle(topic.KioskAnnounce(locationId, "+"), registry.HandleAnnounce)
+	h.ws.Handle(sensorFilter, registry.HandleSensor)
+
+	return &Fleet{h: h, registry: registry, locationId: locationId}
+}
+
+func (f *Fleet) List(c echo.Context) error {
+	return c.JSON(http.StatusOK, f.registry.List())
+}
+
+func (f *Fleet) Get(c echo.Context) error {
+	k, ok := f.registry.Get(f.locationId, c.Param("id"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "kiosk not found")
+	}
+
+	return c.JSON(http.StatusOK, k)
+}
+
+// Delete removes the kiosk, and clears its retained announcement and status so it is not discovered again on
+// restart.
+func (f *Fleet) Delete(c echo.Context) error {
+	kioskId := c.Param("id")
+	if strings.ContainsAny(kioskId, "/+#") {
+		return echo.NewHTTPError(http.StatusBadRequest, "kiosk id must not contain /, + or #")
+	}
+
+	if !f.registry.Delete(f.locationId, kioskId) {
+		return echo.NewHTTPError(http.StatusNotFound, "kiosk not found")
+	}
+
+	// An empty retained message clears the retained message of the topic
+	for _, t := range []string{topic.KioskAnnounce(f.locationId, kioskId), topic.KioskStatus(f.locationId, kioskId)} {
+		if err := f.h.publishMessage(&client.Message{Topic: t, Qos: PubQos, Retained: true}); err != nil {
+			return echo.NewHTTPError(http.StatusBadGateway, err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -6,45 +6,54 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
-	glog "github.com/labstack/gommon/log"
 	"go-mqtt-demo/client"
+	"go-mqtt-demo/history"
+	"go-mqtt-demo/logger"
+	"go-mqtt-demo/topic"
 )
 
+// log logs at the level of the handler subsystem.
+var log = logger.For(logger.SubsystemHandler)
+
 type Handler struct {
 	mqtt *client.Mqtt
 	ws   *client.WebSocket
-}
 
-func New(ca, pubClientId, subClientId, subTopic string) (*Handler, error) {
-	pub, err := client.NewMqtt(ca, pubClientId)
-	if err != nil {
-		return nil, err
-	}
+	// opts are the options the clients are connected with
+	mu   sync.Mutex
+	opts client.Options
+}
 
-	sub, err := client.NewWebSocket(ca, subClientId, subTopic)
+// New creates the handler with clients of the options. With a status topic, the publishing client reports its presence
+// on it. The publishing and subscribing clients may share one connection, see client.NewClients.
+func New(
+	opts client.Options, pubClientId, subClientId string, subTopics []string, statusTopic string, store history.Store,
+) (*Handler, error) {
+	pub, sub, err := client.NewClients(opts, pubClientId, subClientId, statusTopic, subTopics, store)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Handler{mqtt: pub, ws: sub}, nil
+	return &Handler{mqtt: pub, ws: sub, opts: opts}, nil
 }
 
 func (h *Handler) Connect() error {
-	if token := h.mqtt.Connect(); token.Wait() && token.Error() != nil {
-		return token.Error()
-	}
-
-	if token := h.ws.Connect(); token.Wait() && token.Error() != nil {
-		return token.Error()
+	if err := h.mqtt.Connect(); err != nil {
+		return err
 	}
 
-	return nil
+	return h.ws.Connect()
 }
 
 func (h *Handler) Disconnect() {
@@ -67,6 +76,49 @@ func (h *Handler) Disconnect() {
 	wg.Wait()
 }
 
+// Reconfigure reconnects the clients if the options changed, e.g. when the config is reloaded. On failure, the
+// clients stay connected with their previous options.
+func (h *Handler) Reconfigure(opts client.Options) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if reflect.DeepEqual(opts, h.opts) {
+		return nil
+	}
+
+	log.Info("reconnecting with the reloaded broker settings")
+
+	if err := h.mqtt.Reconnect(opts); err != nil {
+		return fmt.Errorf("failed to reconnect publisher: %w", err)
+	}
+
+	if err := h.ws.Reconnect(opts); err != nil {
+		// Both clients are kept on the same options
+		if err := h.mqtt.Reconnect(h.opts); err != nil {
+			log.Error("failed to reconnect publisher with the previous options", "error", err)
+		}
+
+		return fmt.Errorf("failed to reconnect subscriber: %w", err)
+	}
+
+	h.opts = opts
+
+	return nil
+}
+
+// Announce publishes the data as a retained message to the topic on every connection. It should be called before
+// Connect.
+func (h *Handler) Announce(topic string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mqtt.PublishOnConnect(&client.Message{Topic: topic, Payload: payload, Qos: PubQos, Retained: true})
+
+	return nil
+}
+
 const (
 	// PubQos is the QoS when publishing. Preferred to use QoS level 0 when publishing.
 	PubQos = 1
@@ -75,11 +127,15 @@ const (
 	MsgRetained = false
 )
 
+// publishRequest is the body of the publish endpoints.
+type publishRequest struct {
+	Topic      string             `json:"topic"`
+	Data       any                `json:"data"`
+	Properties *client.Properties `json:"properties"`
+}
+
 func (h *Handler) Publish(c echo.Context) error {
-	var p struct {
-		Topic string `json:"topic"`
-		Data  any    `json:"data"`
-	}
+	var p publishRequest
 
 	if err := c.Bind(&p); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -90,17 +146,77 @@ func (h *Handler) Publish(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	if token := h.mqtt.Publish(p.Topic, PubQos, MsgRetained, data); token.Wait() && token.Error() != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	res, err := h.publish(p.Topic, data, p.Properties)
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(
 		http.StatusOK, echo.Map{
-			"message": "ok",
+			"message":      "ok",
+			"reasonCode":   res.ReasonCode,
+			"reasonString": res.ReasonString,
+		},
+	)
+}
+
+// publish publishes the payload to the topic, and returns an HTTP error on failure.
+func (h *Handler) publish(topic string, payload []byte, props *client.Properties) (*client.PublishResult, error) {
+	res, err := h.mqtt.Publish(
+		&client.Message{
+			Topic:      topic,
+			Payload:    payload,
+			Qos:        PubQos,
+			Retained:   MsgRetained,
+			Properties: props,
 		},
 	)
+	if errors.Is(err, client.ErrPropertiesUnsupported) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return res, nil
+}
+
+// publishMessage publishes the message as-is, for the packages that publish on their own.
+func (h *Handler) publishMessage(msg *client.Message) error {
+	_, err := h.mqtt.Publish(msg)
+
+	return err
+}
+
+// connectionStatus is the state of one of the broker connections.
+type connectionStatus struct {
+	Connected bool   `json:"connected"`
+	Broker    string `json:"broker,omitempty"`
+	Protocol  string `json:"protocol"`
 }
 
+// Status returns the state of the publishing and subscribing connections, with the broker each is connected to.
+func (h *Handler) Status(c echo.Context) error {
+	return c.JSON(
+		http.StatusOK, echo.Map{
+			"publisher": connectionStatus{
+				Connected: h.mqtt.IsConnected(),
+				Broker:    h.mqtt.Broker(),
+				Protocol:  h.mqtt.ProtocolVersion().String(),
+			},
+			"subscriber": connectionStatus{
+				Connected: h.ws.IsConnected(),
+				Broker:    h.ws.Broker(),
+				Protocol:  h.ws.ProtocolVersion().String(),
+			},
+		},
+	)
+}
+
+// SseHeartbeatInterval is how often a comment is sent to keep idle SSE streams from being closed by proxies.
+const SseHeartbeatInterval = 15 * time.Second
+
 func (h *Handler) SubscribeSse(c echo.Context) error {
 	c.Response().Header().Set("Content-Type", "text/event-stream")
 	c.Response().Header().Set("Cache-Control", "no-cache")
@@ -108,18 +224,102 @@ func (h *Handler) SubscribeSse(c echo.Context) error {
 
 	f, ok := c.Response().Writer.(http.Flusher)
 	if !ok {
-		glog.Errorf("failed to flush response stream: %v", c.Response().Writer)
+		log.Error("response stream cannot be flushed")
+
+		return nil
+	}
 
+	// Browsers send the ID of the last event received when reconnecting
+	lastEventId, _ := strconv.ParseInt(c.Request().Header.Get("Last-Event-ID"), 10, 64)
+
+	eventId := time.Now().UnixNano()
+	messages := make(chan client.SseMessage, client.DefaultBufferSize)
+	ready := make(chan struct{})
+	h.ws.SseEvent <- client.SseEvent{Id: eventId, Messages: messages, Ready: ready, Action: "add"}
+
+	defer func() {
+		h.ws.SseEvent <- client.SseEvent{Id: eventId, Action: "remove"}
+	}()
+
+	select {
+	case <-c.Request().Context().Done():
 		return nil
+	case <-ready:
+	}
+
+	c.Response().WriteHeader(http.StatusOK)
+
+	// Live messages are buffered while the history is replayed, so skip those that were already replayed. Only the
+	// replayed messages count, as the Last-Event-ID may be from a history that was since reset.
+	replayed, err := h.ws.Replay(lastEventId)
+	if err != nil {
+		log.Error("failed to replay messages", "error", err)
 	}
 
-	done := make(chan bool)
-	h.ws.SseEvent <- client.SseEvent{Writer: c.Response().Writer, Done: done}
+	var lastId int64
+
+	for _, msg := range replayed {
+		if err := writeSseMessage(c.Response(), msg); err != nil {
+			log.Error("failed to write message", "error", err)
+
+			return nil
+		}
+
+		lastId = msg.Id
+	}
 
-	<-done
 	f.Flush()
 
-	return nil
+	heartbeat := time.NewTicker(SseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			// A message without an event ID is not in the history, so it cannot have been replayed
+			if msg.Id != 0 && msg.Id <= lastId {
+				continue
+			}
+
+			if err := writeSseMessage(c.Response(), msg); err != nil {
+				log.Error("failed to write message", "error", err)
+
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Response(), ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		}
+
+		f.Flush()
+	}
+}
+
+// writeSseMessage writes the message as an SSE event. Messages received while offline have the "offline" event type.
+// A message without an event ID has no id field, so the client keeps its last event ID.
+func writeSseMessage(w io.Writer, msg client.SseMessage) error {
+	if msg.Offline {
+		if _, err := fmt.Fprint(w, "event: offline\n"); err != nil {
+			return err
+		}
+	}
+
+	if msg.Id == 0 {
+		_, err := fmt.Fprintf(w, "data: %s\n\n", msg.Data)
+
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", msg.Id, msg.Data)
+
+	return err
 }
 
 func (h *Handler) SubscribeWs(c echo.Context) error {
@@ -127,7 +327,7 @@ func (h *Handler) SubscribeWs(c echo.Context) error {
 	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
 
 	if err != nil {
-		glog.Errorf("websocket upgrade failed: %v", err)
+		log.Error("websocket upgrade failed", "error", err)
 
 		return err
 	}
@@ -147,3 +347,41 @@ func (h *Handler) SubscribeWs(c echo.Context) error {
 
 	return nil
 }
+
+func (h *Handler) History(c echo.Context) error {
+	var q history.Query
+
+	if filter := c.QueryParam("topic"); filter != "" {
+		if err := topic.ValidateFilter(filter); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		q.Topic = filter
+	}
+
+	if err := echo.QueryParamsBinder(c).
+		Time("since", &q.Since, time.RFC3339).
+		Time("until", &q.Until, time.RFC3339).
+		Int64("after", &q.After).
+		Int("limit", &q.Limit).
+		BindError(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	records, next, err := history.Find(h.ws.History, q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	// An empty page is returned as an empty list rather than null
+	if records == nil {
+		records = []history.Record{}
+	}
+
+	res := echo.Map{"messages": records}
+	if next > 0 {
+		res["next"] = next
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
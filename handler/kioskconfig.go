@@ -0,0 +1,127 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"go-mqtt-demo/client"
+	"go-mqtt-demo/kioskconfig"
+)
+
+// KioskConfig applies the configs received by a kiosk, keeps the applied config on local disk, and acks each
+// versioned config to admin.
+type KioskConfig struct {
+	h        *Handler
+	merger   *kioskconfig.Merger
+	ackTopic string
+	path     string
+
+	// mu serializes the writes to the file
+	mu sync.Mutex
+}
+
+// NewKioskConfig merges the configs received on the topics, given from the lowest to the highest precedence. The
+// config applied by an earlier run is loaded from the file, so it is available before connecting to the broker.
+func NewKioskConfig(h *Handler, ackTopic, path string, topics ...string) (*KioskConfig, error) {
+	k := &KioskConfig{h: h, merger: kioskconfig.NewMerger(topics...), ackTopic: ackTopic, path: path}
+
+	applied, err := kioskconfig.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied config: %w", err)
+	}
+
+	k.merger.Restore(applied.Sources)
+
+	for _, t := range topics {
+		h.ws.Handle(t, k.apply)
+	}
+
+	return k, nil
+}
+
+func (k *KioskConfig) apply(msg *client.Message) {
+	env := kioskconfig.Unwrap(msg.Payload)
+	ack := kioskconfig.Ack{Version: env.Version, Topic: msg.Topic, Status: kioskconfig.AckApplied}
+
+	if err := k.merger.Set(msg.Topic, env.Version, env.Data); err != nil {
+		log.Error("failed to apply config", "version", env.Version, "topic", msg.Topic, "error", err)
+
+		ack.Status = kioskconfig.AckFailed
+		ack.Error = err.Error()
+	} else {
+		k.save()
+	}
+
+	// Configs published without an envelope have no version to ack
+	if env.Version == 0 {
+		return
+	}
+
+	// Waiting for the broker within a message handler would block the delivery of other messages
+	go k.ack(ack)
+}
+
+func (k *KioskConfig) save() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := kioskconfig.Save(k.path, k.merger.Sources()); err != nil {
+		log.Error("failed to save applied config", "error", err)
+	}
+}
+
+func (k *KioskConfig) ack(ack kioskconfig.Ack) {
+	payload, err := json.Marshal(ack)
+	if err != nil {
+		log.Error("failed to encode ack", "error", err)
+
+		return
+	}
+
+	if _, err := k.h.mqtt.Publish(&client.Message{Topic: k.ackTopic, Payload: payload, Qos: PubQos}); err != nil {
+		log.Error("failed to ack config", "version", ack.Version, "topic", k.ackTopic, "error", err)
+	}
+}
+
+func (k *KioskConfig) Effective(c echo.Context) error {
+	return c.JSON(
+		http.StatusOK, echo.Map{
+			"config":  k.merger.Effective(),
+			"sources": k.merger.Sources(),
+		},
+	)
+}
+
+// Current returns the applied config as kept on local disk.
+func (k *KioskConfig) Current(c echo.Context) error {
+	k.mu.Lock()
+	applied, err := kioskconfig.Load(k.path)
+	k.mu.Unlock()
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	m := kioskconfig.NewMerger(k.merger.Topics()...)
+	m.Restore(applied.Sources)
+
+	return c.JSON(
+		http.StatusOK, echo.Map{
+			"config":  m.Effective(),
+			"sources": applied.Sources,
+			"savedAt": applied.SavedAt,
+		},
+	)
+}
+
+// Config returns the effective config.
+func (k *KioskConfig) Config() map[string]any {
+	return k.merger.Effective()
+}
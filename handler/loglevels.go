@@ -0,0 +1,118 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go-mqtt-demo/logger"
+	"go-mqtt-demo/secret"
+)
+
+// LogLevels handles the log level endpoints of admin and kiosk, which require the admin token.
+type LogLevels struct {
+	mu    sync.RWMutex
+	token secret.Value
+}
+
+// logLevels are the levels of the logs. Subsystems without a level follow the default level.
+type logLevels struct {
+	Default    slog.Level            `json:"default"`
+	Subsystems map[string]slog.Level `json:"subsystems"`
+}
+
+func NewLogLevels(token secret.Value) *LogLevels {
+	return &LogLevels{token: token}
+}
+
+// SetToken replaces the admin token, e.g. when the config is reloaded.
+func (l *LogLevels) SetToken(token secret.Value) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.token = token
+}
+
+// Authorize accepts the requests with the admin token as bearer token. Without an admin token, every request is
+// rejected.
+func (l *LogLevels) Authorize() echo.MiddlewareFunc {
+	return middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{Validator: l.validate})
+}
+
+func (l *LogLevels) validate(key string, _ echo.Context) (bool, error) {
+	l.mu.RLock()
+	token := l.token
+	l.mu.RUnlock()
+
+	if token.IsZero() {
+		return false, nil
+	}
+
+	s, err := token.Reveal()
+	if err != nil {
+		log.Error("failed to read admin token", "error", err)
+
+		return false, nil
+	}
+
+	return subtle.ConstantTimeCompare([]byte(key), []byte(s)) == 1, nil
+}
+
+func (l *LogLevels) Get(c echo.Context) error {
+	return c.JSON(http.StatusOK, logLevels{Default: logger.Level(), Subsystems: logger.SubsystemLevels()})
+}
+
+// Update sets the levels of the subsystems in the body, e.g. {"mqtt": "debug"}, until the config is reloaded. An
+// empty level makes the subsystem follow the default level again.
+func (l *LogLevels) Update(c echo.Context) error {
+	var body map[string]string
+
+	if err := c.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	// The levels are checked first, so an invalid body changes none of them
+	levels := make(map[string]*slog.Level, len(body))
+
+	for name, value := range body {
+		if !slices.Contains(logger.Subsystems(), name) {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown log subsystem %q", name))
+		}
+
+		if value == "" {
+			levels[name] = nil
+
+			continue
+		}
+
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		levels[name] = &level
+	}
+
+	for name, level := range levels {
+		if level == nil {
+			_ = logger.ResetLevel(name)
+
+			continue
+		}
+
+		_ = logger.SetLevel(name, *level)
+	}
+
+	log.Info("log levels updated", "levels", body, "remote_ip", c.RealIP())
+
+	return l.Get(c)
+}
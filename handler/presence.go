@@ -0,0 +1,81 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"go-mqtt-demo/presence"
+)
+
+// Presence handles the kiosk presence endpoints of admin.
+type Presence struct {
+	table *presence.Table
+}
+
+// NewPresence tracks the kiosks from the status topic filter, and marks kiosks as seen from the activity filter.
+func NewPresence(h *Handler, statusFilter, activityFilter string) *Presence {
+	table := presence.NewTable()
+
+	h.ws.Handle(statusFilter, table.HandleStatus)
+	h.ws.Handle(activityFilter, table.Touch)
+
+	return &Presence{table: table}
+}
+
+func (p *Presence) List(c echo.Context) error {
+	return c.JSON(http.StatusOK, p.table.List())
+}
+
+// SubscribeWs sends the presence of every known kiosk, followed by each change.
+func (p *Presence) SubscribeWs(c echo.Context) error {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+
+	if err != nil {
+		log.Error("websocket upgrade failed", "error", err)
+
+		return err
+	}
+
+	defer conn.Close()
+
+	updates, unsubscribe := p.table.Subscribe()
+	defer unsubscribe()
+
+	for _, k := range p.table.List() {
+		if err := conn.WriteJSON(k); err != nil {
+			return nil
+		}
+	}
+
+	// Reading detects when the connection is closed by the client
+	closed := make(chan struct{})
+
+	go func() {
+		defer close(closed)
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return nil
+		case k := <-updates:
+			if err := conn.WriteJSON(k); err != nil {
+				log.Error("failed to send presence", "error", err)
+
+				return nil
+			}
+		}
+	}
+}
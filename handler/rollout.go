@@ -0,0 +1,219 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go-mqtt-demo/configstore"
+	"go-mqtt-demo/kioskconfig"
+	"go-mqtt-demo/rollout"
+)
+
+// Rollout publishes versioned configs from admin, keeps them in the config store, and tracks their apply status from
+// the acks of the kiosks.
+type Rollout struct {
+	h       *Handler
+	fleet   *Fleet
+	tracker *rollout.Tracker
+	store   configstore.Store
+}
+
+func NewRollout(h *Handler, f *Fleet, ackFilter string, store configstore.Store) *Rollout {
+	tracker := rollout.NewTracker()
+
+	h.ws.Handle(ackFilter, tracker.HandleAck)
+	h.ws.Handle(ackFilter, f.registry.HandleAck)
+
+	return &Rollout{h: h, fleet: f, tracker: tracker, store: store}
+}
+
+// configRequest is the body of the config publish endpoint.
+type configRequest struct {
+	publishRequest
+	Author string `json:"author"`
+}
+
+func (r *Rollout) Publish(c echo.Context) error {
+	var p configRequest
+
+	if err := c.Bind(&p); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	data, err := json.Marshal(p.Data)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return r.publish(
+		c, &configstore.Version{
+			Topic:       p.Topic,
+			Author:      p.Author,
+			PublishedAt: time.Now(),
+			Data:        data,
+			Properties:  p.Properties,
+		},
+	)
+}
+
+// Rollback republishes an earlier config version to its topic, as a new version.
+func (r *Rollout) Rollback(c echo.Context) error {
+	old, err := r.findVersion(c.Param("version"))
+	if err != nil {
+		return err
+	}
+
+	var p struct {
+		Author string `json:"author"`
+	}
+
+	if err := c.Bind(&p); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	return r.publish(
+		c, &configstore.Version{
+			Topic:       old.Topic,
+			Author:      p.Author,
+			PublishedAt: time.Now(),
+			Data:        old.Data,
+			Properties:  old.Properties,
+			RollbackOf:  old.Version,
+		},
+	)
+}
+
+// publish stores the config, which assigns its version, and publishes it in an envelope.
+func (r *Rollout) publish(c echo.Context, v *configstore.Version) error {
+	if err := r.store.Save(v); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	payload, err := json.Marshal(kioskconfig.Wrap(v.Version, v.Data))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	// Tracking starts before publishing, since acks can arrive before the publish completes
+	r.tracker.Start(v.Version, v.Topic, r.fleet.registry.Targets(v.Topic))
+
+	res, err := r.h.publish(v.Topic, payload, v.Properties)
+	if err != nil {
+		r.tracker.Remove(v.Version)
+
+		// Only the configs that were published are kept
+		if err := r.store.Delete(v.Version); err != nil {
+			log.Error("failed to delete unpublished config", "version", v.Version, "error", err)
+		}
+
+		return err
+	}
+
+	return c.JSON(
+		http.StatusOK, echo.Map{
+			"message":      "ok",
+			"version":      v.Version,
+			"reasonCode":   res.ReasonCode,
+			"reasonString": res.ReasonString,
+		},
+	)
+}
+
+// Versions returns the stored config versions, latest first. The limit query parameter defaults to all of them.
+func (r *Rollout) Versions(c echo.Context) error {
+	limit := 0
+
+	if l := c.QueryParam("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be a non-negative number")
+		}
+	}
+
+	versions, err := r.store.List(limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, versions)
+}
+
+func (r *Rollout) Version(c echo.Context) error {
+	v, err := r.findVersion(c.Param("version"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, v)
+}
+
+// Diff returns the changes between the config versions of the from and to query parameters.
+func (r *Rollout) Diff(c echo.Context) error {
+	from, err := r.findVersion(c.QueryParam("from"))
+	if err != nil {
+		return err
+	}
+
+	to, err := r.findVersion(c.QueryParam("to"))
+	if err != nil {
+		return err
+	}
+
+	changes, err := configstore.Diff(from.Data, to.Data)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(
+		http.StatusOK, echo.Map{
+			"from":    from.Version,
+			"to":      to.Version,
+			"changes": changes,
+		},
+	)
+}
+
+func (r *Rollout) findVersion(param string) (*configstore.Version, error) {
+	version, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid config version %q", param))
+	}
+
+	v, err := r.store.Get(version)
+	if errors.Is(err, configstore.ErrNotFound) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return v, nil
+}
+
+func (r *Rollout) List(c echo.Context) error {
+	return c.JSON(http.StatusOK, r.tracker.List())
+}
+
+func (r *Rollout) Get(c echo.Context) error {
+	version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	s, ok := r.tracker.Get(version)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "config version not found")
+	}
+
+	return c.JSON(http.StatusOK, s)
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go-mqtt-demo/rpc"
+	"go-mqtt-demo/topic"
+)
+
+const (
+	// DefaultRPCTimeout is how long admin waits for the response of a kiosk, unless the request sets a timeout.
+	DefaultRPCTimeout = 10 * time.Second
+
+	// MaxRPCTimeout is the longest timeout a request may set.
+	MaxRPCTimeout = time.Minute
+)
+
+// RPC calls the methods of the kiosks from admin, and waits for their responses.
+type RPC struct {
+	caller     *rpc.Caller
+	locationId string
+}
+
+// NewRPC receives the responses of the kiosks on the response topic, which must be unique to this admin instance.
+func NewRPC(h *Handler, locationId, responseTopic string) *RPC {
+	caller := rpc.NewCaller(responseTopic, h.mqtt.ProtocolVersion(), h.publishMessage)
+
+	h.ws.Handle(responseTopic, caller.HandleResponse)
+
+	return &RPC{caller: caller, locationId: locationId}
+}
+
+// Call sends the request body as the params of the method to the kiosk. The timeout query parameter is a duration,
+// e.g. 30s.
+func (r *RPC) Call(c echo.Context) error {
+	// Echo keeps the escapes of the path, e.g. %2B for +
+	kioskId, err := url.PathUnescape(c.Param("id"))
+	if err != nil || strings.ContainsAny(kioskId, "/+#") {
+		return echo.NewHTTPError(http.StatusBadRequest, "kiosk id must not contain /, + or #")
+	}
+
+	method, err := url.PathUnescape(c.Param("method"))
+	if err != nil || strings.ContainsAny(method, "/+#") {
+		return echo.NewHTTPError(http.StatusBadRequest, "method must not contain /, + or #")
+	}
+
+	timeout := DefaultRPCTimeout
+
+	if t := c.QueryParam("timeout"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil || d <= 0 || d > MaxRPCTimeout {
+			return echo.NewHTTPError(http.StatusBadRequest, "timeout must be a duration up to "+MaxRPCTimeout.String())
+		}
+
+		timeout = d
+	}
+
+	params, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	if len(params) > 0 && !json.Valid(params) {
+		return echo.NewHTTPError(http.StatusBadRequest, "params must be JSON")
+	}
+
+	res, err := r.caller.Call(topic.KioskRPC(r.locationId, kioskId, method), params, timeout)
+	if errors.Is(err, rpc.ErrTimeout) {
+		return echo.NewHTTPError(http.StatusGatewayTimeout, err)
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, err)
+	}
+
+	if res.Error != "" {
+		return c.JSON(http.StatusBadGateway, echo.Map{"error": res.Error})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"result": res.Result})
+}
+
+// RPCServer serves the RPC requests to a kiosk with the methods registered on it.
+type RPCServer struct {
+	*rpc.Server
+}
+
+// NewRPCServer receives the requests on the filter, whose last level is the method.
+func NewRPCServer(h *Handler, requestFilter string) *RPCServer {
+	s := &RPCServer{Server: rpc.NewServer(h.publishMessage)}
+
+	h.ws.Handle(requestFilter, s.HandleRequest)
+
+	return s
+}
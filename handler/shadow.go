@@ -0,0 +1,224 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"go-mqtt-demo/client"
+	"go-mqtt-demo/shadow"
+	"go-mqtt-demo/topic"
+)
+
+// ShadowSyncDelay is how long after subscribing the shadows are written, for the retained documents to be received
+// first. Otherwise a write would start over at version 1, overwriting a newer retained document.
+const ShadowSyncDelay = time.Second
+
+// Shadow handles the device shadow endpoints. Admin writes the desired documents and publishes the deltas, while a
+// kiosk writes its reported document. All documents are retained, so the shadows are rebuilt on restart.
+type Shadow struct {
+	h          *Handler
+	registry   *shadow.Registry
+	locationId string
+
+	// kioskId is only set on a kiosk
+	kioskId string
+
+	// writeMu serializes the writes, so versions are assigned in order
+	writeMu sync.Mutex
+
+	// deltaMu serializes the publishing of deltas, so the latest retained delta is always the latest state
+	deltaMu sync.Mutex
+}
+
+// NewAdminShadow tracks the shadows of all kiosks of the location.
+func NewAdminShadow(h *Handler, locationId string) *Shadow {
+	return newShadow(h, locationId, "")
+}
+
+// NewKioskShadow tracks the shadow of the kiosk itself.
+func NewKioskShadow(h *Handler, locationId, kioskId string) *Shadow {
+	return newShadow(h, locationId, kioskId)
+}
+
+func newShadow(h *Handler, locationId, kioskId string) *Shadow {
+	s := &Shadow{h: h, registry: shadow.NewRegistry(), locationId: locationId, kioskId: kioskId}
+
+	filterId := kioskId
+	if filterId == "" {
+		filterId = "+"
+	}
+
+	h.ws.Handle(topic.KioskShadow(locationId, filterId, shadow.Desired), s.handle(shadow.Desired))
+	h.ws.Handle(topic.KioskShadow(locationId, filterId, shadow.Reported), s.handle(shadow.Reported))
+
+	return s
+}
+
+func (s *Shadow) handle(name string) client.MessageHandler {
+	return func(msg *client.Message) {
+		update, changed := s.registry.Handle(msg, name)
+
+		// Waiting for the broker within a message handler would block the delivery of other messages
+		if changed && s.kioskId == "" {
+			go s.publishDelta(update.KioskId)
+		}
+	}
+}
+
+func (s *Shadow) publishDelta(kioskId string) {
+	s.deltaMu.Lock()
+	defer s.deltaMu.Unlock()
+
+	delta := s.registry.Get(s.locationId, kioskId).Delta
+	if delta == nil {
+		delta = &shadow.Document{State: map[string]any{}, UpdatedAt: time.Now()}
+	}
+
+	if err := s.publishDocument(kioskId, shadow.Delta, delta); err != nil {
+		log.Error("failed to publish shadow delta", "kiosk_id", kioskId, "error", err)
+	}
+}
+
+func (s *Shadow) publishDocument(kioskId, name string, doc *shadow.Document) error {
+	payload, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return s.h.publishMessage(
+		&client.Message{
+			Topic:    topic.KioskShadow(s.locationId, kioskId, name),
+			Payload:  payload,
+			Qos:      PubQos,
+			Retained: true,
+		},
+	)
+}
+
+// write publishes the state as the next version of the document, and applies it to the shadow.
+func (s *Shadow) write(c echo.Context, kioskId, name string) error {
+	var state map[string]any
+	if err := json.NewDecoder(c.Request().Body).Decode(&state); err != nil || state == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "state must be a JSON object")
+	}
+
+	if at := s.h.ws.SubscribedAt(); at.IsZero() || time.Since(at) < ShadowSyncDelay {
+		c.Response().Header().Set("Retry-After", "1")
+
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "shadow not received from the broker yet")
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	current := s.registry.Get(s.locationId, kioskId)
+
+	doc := &shadow.Document{State: state, Version: 1, UpdatedAt: time.Now()}
+
+	if name == shadow.Desired && current.Desired != nil {
+		doc.Version = current.Desired.Version + 1
+	} else if name == shadow.Reported && current.Reported != nil {
+		doc.Version = current.Reported.Version + 1
+	}
+
+	if err := s.publishDocument(kioskId, name, doc); err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, err)
+	}
+
+	// The document may have already been applied when received back from the broker
+	update, changed := s.registry.Set(s.locationId, kioskId, name, *doc)
+	if !changed {
+		update = s.registry.Get(s.locationId, kioskId)
+	} else if s.kioskId == "" {
+		go s.publishDelta(kioskId)
+	}
+
+	return c.JSON(http.StatusOK, update)
+}
+
+// UpdateDesired replaces the desired state of the kiosk with the request body.
+func (s *Shadow) UpdateDesired(c echo.Context) error {
+	kioskId := c.Param("id")
+	if strings.ContainsAny(kioskId, "/+#") {
+		return echo.NewHTTPError(http.StatusBadRequest, "kiosk id must not contain /, + or #")
+	}
+
+	return s.write(c, kioskId, shadow.Desired)
+}
+
+// UpdateReported replaces the reported state of the kiosk with the request body.
+func (s *Shadow) UpdateReported(c echo.Context) error {
+	return s.write(c, s.kioskId, shadow.Reported)
+}
+
+func (s *Shadow) List(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.registry.List())
+}
+
+// Get returns the shadow of the kiosk of the id parameter, or of the kiosk itself.
+func (s *Shadow) Get(c echo.Context) error {
+	kioskId := s.kioskId
+	if kioskId == "" {
+		kioskId = c.Param("id")
+	}
+
+	return c.JSON(http.StatusOK, s.registry.Get(s.locationId, kioskId))
+}
+
+// SubscribeWs sends the known shadows, followed by each change.
+func (s *Shadow) SubscribeWs(c echo.Context) error {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+
+	if err != nil {
+		log.Error("websocket upgrade failed", "error", err)
+
+		return err
+	}
+
+	defer conn.Close()
+
+	updates, unsubscribe := s.registry.Subscribe()
+	defer unsubscribe()
+
+	for _, sh := range s.registry.List() {
+		if err := conn.WriteJSON(sh); err != nil {
+			return nil
+		}
+	}
+
+	// Reading detects when the connection is closed by the client
+	closed := make(chan struct{})
+
+	go func() {
+		defer close(closed)
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return nil
+		case sh := <-updates:
+			if err := conn.WriteJSON(sh); err != nil {
+				log.Error("failed to send shadow", "error", err)
+
+				return nil
+			}
+		}
+	}
+}
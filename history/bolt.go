@@ -0,0 +1,173 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var messagesBucket = []byte("messages")
+
+// BoltStore keeps the history in a local bbolt database file. Records are keyed by their big-endian sequence number,
+// so the keys are iterated in order.
+type BoltStore struct {
+	db        *bolt.DB
+	retention Retention
+
+	// count is the number of records, only updated within write transactions
+	count int
+}
+
+func OpenBolt(path string, retention Retention) (*BoltStore, error) {
+	// The timeout prevents blocking forever when another process already holds the file lock
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &BoltStore{db: db, retention: retention}
+
+	err = db.Update(
+		func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists(messagesBucket)
+			if err != nil {
+				return err
+			}
+
+			s.count = b.Stats().KeyN
+
+			return s.prune(b, time.Now())
+		},
+	)
+	if err != nil {
+		_ = db.Close()
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func seqKey(seq int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(seq))
+
+	return key
+}
+
+func (s *BoltStore) Append(rec *Record) error {
+	return s.db.Update(
+		func(tx *bolt.Tx) error {
+			b := tx.Bucket(messagesBucket)
+
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			rec.Seq = int64(seq)
+
+			value, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+
+			if err := b.Put(seqKey(rec.Seq), value); err != nil {
+				return err
+			}
+
+			s.count++
+
+			return s.prune(b, time.Now())
+		},
+	)
+}
+
+// prune deletes the oldest records that are past the retention limits.
+func (s *BoltStore) prune(b *bolt.Bucket, now time.Time) error {
+	excess := 0
+	if s.retention.MaxCount > 0 {
+		excess = s.count - s.retention.MaxCount
+	}
+
+	// Keys are collected first, since deleting while iterating makes the cursor skip records
+	var keys [][]byte
+
+	c := b.Cursor()
+
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if len(keys) >= excess {
+			var rec Record
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return err
+			}
+
+			if !s.retention.expired(rec.ReceivedAt, now) {
+				break
+			}
+		}
+
+		keys = append(keys, k)
+	}
+
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+
+		s.count--
+	}
+
+	return nil
+}
+
+func (s *BoltStore) Since(seq int64, limit int) ([]Record, error) {
+	var records []Record
+
+	err := s.db.View(
+		func(tx *bolt.Tx) error {
+			c := tx.Bucket(messagesBucket).Cursor()
+
+			for k, v := c.Seek(seqKey(seq + 1)); k != nil; k, v = c.Next() {
+				var rec Record
+				if err := json.Unmarshal(v, &rec); err != nil {
+					return err
+				}
+
+				records = append(records, rec)
+
+				if limit > 0 && len(records) == limit {
+					break
+				}
+			}
+
+			return nil
+		},
+	)
+
+	return records, err
+}
+
+func (s *BoltStore) LastSeq() (int64, error) {
+	var seq int64
+
+	err := s.db.View(
+		func(tx *bolt.Tx) error {
+			seq = int64(tx.Bucket(messagesBucket).Sequence())
+
+			return nil
+		},
+	)
+
+	return seq, err
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package history
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the history in memory, so it is lost on restart.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	records   []Record
+	seq       int64
+	retention Retention
+}
+
+func NewMemory(retention Retention) *MemoryStore {
+	return &MemoryStore{retention: retention}
+}
+
+func (s *MemoryStore) Append(rec *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	rec.Seq = s.seq
+
+	s.records = append(s.records, *rec)
+	s.prune(time.Now())
+
+	return nil
+}
+
+func (s *MemoryStore) prune(now time.Time) {
+	i := 0
+	for i < len(s.records) && s.retention.expired(s.records[i].ReceivedAt, now) {
+		i++
+	}
+
+	if max := s.retention.MaxCount; max > 0 && len(s.records)-i > max {
+		i = len(s.records) - max
+	}
+
+	s.records = s.records[i:]
+}
+
+func (s *MemoryStore) Since(seq int64, limit int) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Records are kept in order of their sequence number
+	i := sort.Search(
+		len(s.records), func(i int) bool {
+			return s.records[i].Seq > seq
+		},
+	)
+
+	records := s.records[i:]
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	return append([]Record(nil), records...), nil
+}
+
+func (s *MemoryStore) LastSeq() (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.seq, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
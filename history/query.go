@@ -0,0 +1,73 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package history
+
+import (
+	"time"
+
+	"go-mqtt-demo/topic"
+)
+
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+
+	// scanBatch is how many records are read from the store at a time while filtering
+	scanBatch = 500
+)
+
+// Query selects records by topic filter and receive time. After is the sequence number to continue from when paging.
+type Query struct {
+	Topic string
+	Since time.Time
+	Until time.Time
+	After int64
+	Limit int
+}
+
+// Find returns the records matching the query, in order, at most MaxQueryLimit at a time. The next cursor is the
+// sequence number to pass as After for the following page, or 0 when there are no more records.
+func Find(s Store, q Query) (records []Record, next int64, err error) {
+	limit := min(q.Limit, MaxQueryLimit)
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+
+	after := q.After
+
+	for {
+		batch, err := s.Since(after, scanBatch)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, rec := range batch {
+			after = rec.Seq
+
+			// Records are received in order of their sequence number, so nothing after this one can match
+			if !q.Until.IsZero() && rec.ReceivedAt.After(q.Until) {
+				return records, 0, nil
+			}
+
+			if !q.Since.IsZero() && rec.ReceivedAt.Before(q.Since) {
+				continue
+			}
+
+			if q.Topic != "" && !topic.Match(q.Topic, rec.Topic) {
+				continue
+			}
+
+			records = append(records, rec)
+
+			if len(records) == limit {
+				return records, rec.Seq, nil
+			}
+		}
+
+		if len(batch) < scanBatch {
+			return records, 0, nil
+		}
+	}
+}
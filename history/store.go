@@ -0,0 +1,63 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Record is a message received from the broker. Data is the JSON encoded message, as relayed to the UI clients.
+type Record struct {
+	Seq        int64           `json:"seq"`
+	Topic      string          `json:"topic"`
+	ReceivedAt time.Time       `json:"receivedAt"`
+	Offline    bool            `json:"offline"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Retention limits how many records a store keeps, and for how long. Zero values mean no limit.
+type Retention struct {
+	MaxCount int
+	MaxAge   time.Duration
+}
+
+// expired reports whether a record received at the given time is older than the maximum age.
+func (r Retention) expired(receivedAt, now time.Time) bool {
+	return r.MaxAge > 0 && now.Sub(receivedAt) > r.MaxAge
+}
+
+// Store keeps the message history. Sequence numbers are assigned by the store, and keep increasing across restarts
+// of a durable store.
+type Store interface {
+	// Append assigns the next sequence number to the record and stores it.
+	Append(rec *Record) error
+
+	// Since returns up to limit records after the given sequence number, in order. A limit of 0 returns all of them.
+	Since(seq int64, limit int) ([]Record, error)
+
+	// LastSeq returns the sequence number of the latest record.
+	LastSeq() (int64, error)
+
+	Close() error
+}
+
+const (
+	Bolt   = "bolt"
+	Memory = "memory"
+)
+
+// Open creates the store of the given kind. The path is only used by durable stores.
+func Open(kind, path string, retention Retention) (Store, error) {
+	switch kind {
+	case "", Bolt:
+		return OpenBolt(path, retention)
+	case Memory:
+		return NewMemory(retention), nil
+	default:
+		return nil, fmt.Errorf("unsupported history store %q", kind)
+	}
+}
@@ -5,58 +5,185 @@
 package main
 
 import (
-	"fmt"
+	"encoding/json"
+	"errors"
+	"flag"
 	"html/template"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"time"
 
-	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
-	glog "github.com/labstack/gommon/log"
 	"go-mqtt-demo/config"
+	"go-mqtt-demo/embedded"
+	"go-mqtt-demo/fleet"
 	"go-mqtt-demo/handler"
+	"go-mqtt-demo/history"
 	tmpl "go-mqtt-demo/html/template"
 	"go-mqtt-demo/logger"
+	"go-mqtt-demo/topic"
 )
 
+// version is the kiosk software version, set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
-	if err := godotenv.Load(); err != nil {
-		glog.Fatal("error loading .env file")
+	cfg, err := config.New(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
 
-	logger.Init()
+	if err != nil {
+		logger.Fatal("failed to start", "error", err)
+	}
 
-	e := echo.New()
+	// The config is printed even if invalid, to find the source of the invalid value
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			logger.Fatal("failed to start", "error", err)
+		}
+
+		if err := cfg.Validate(); err != nil {
+			logger.Fatal("failed to start", "error", err)
+		}
+
+		if err := cfg.ValidateKiosk(); err != nil {
+			logger.Fatal("failed to start", "error", err)
+		}
+
+		return
+	}
 
-	cfg, err := config.New()
+	if err := cfg.ValidateKiosk(); err != nil {
+		logger.Fatal("failed to start", "error", err)
+	}
+
+	logOpts := cfg.LogOptions()
+	logOpts.Attrs = []any{"location_id", cfg.LocationId, "kiosk_id", cfg.KioskId}
+
+	logs, err := logger.Init(logOpts)
 	if err != nil {
-		glog.Fatal(err)
+		logger.Fatal("failed to start", "error", err)
 	}
 
+	e := echo.New()
+	logger.Echo(e)
+
 	frontend(e, cfg)
 
-	const ca = "emqxsl-ca.crt"
+	// Closed in order on shutdown, after disconnecting from the broker
+	var closers []io.Closer
+
+	if cfg.EmbeddedBroker {
+		b, err := embedded.Start(
+			embedded.Options{TCPAddress: ":" + cfg.BrokerPort, WSAddress: ":" + cfg.BrokerWSPort},
+		)
+		if err != nil {
+			logger.Fatal("failed to start", "error", err)
+		}
+
+		closers = append(closers, b)
+	}
+
+	// Config topics from the lowest to the highest precedence: location, groups, then the kiosk itself
+	subTopics := []string{topic.LocationConfig(cfg.LocationId)}
+	for _, group := range cfg.KioskGroups {
+		subTopics = append(subTopics, topic.GroupConfig(cfg.LocationId, group))
+	}
+
+	subTopics = append(subTopics, topic.KioskConfig(cfg.LocationId, cfg.KioskId))
+
+	store, err := history.Open(
+		cfg.HistoryStore, cfg.HistoryPath,
+		history.Retention{MaxCount: cfg.HistoryMaxCount, MaxAge: cfg.HistoryMaxAge},
+	)
+	if err != nil {
+		logger.Fatal("failed to start", "error", err)
+	}
+
+	statusTopic := topic.KioskStatus(cfg.LocationId, cfg.KioskId)
 
-	subTopic := fmt.Sprintf("location/%v/kiosk/config", cfg.LocationId)
+	h, err := handler.New(cfg.ClientOptions(), "pub_sensor_client", "sub_cfg_client", subTopics, statusTopic, store)
+	if err != nil {
+		logger.Fatal("failed to start", "error", err)
+	}
+
+	announcement := fleet.Announcement{Version: version, Groups: cfg.KioskGroups}
+	if err := h.Announce(topic.KioskAnnounce(cfg.LocationId, cfg.KioskId), announcement); err != nil {
+		logger.Fatal("failed to start", "error", err)
+	}
 
-	h, err := handler.New(ca, "pub_sensor_client", "sub_cfg_client", subTopic)
+	kc, err := handler.NewKioskConfig(
+		h, topic.KioskConfigAck(cfg.LocationId, cfg.KioskId), cfg.KioskConfigPath, subTopics...,
+	)
 	if err != nil {
-		glog.Fatal(err)
+		logger.Fatal("failed to start", "error", err)
 	}
 
+	sh := handler.NewKioskShadow(h, cfg.LocationId, cfg.KioskId)
+
+	rs := handler.NewRPCServer(h, topic.KioskRPC(cfg.LocationId, cfg.KioskId, "+"))
+	registerMethods(rs, kc)
+
 	if err := h.Connect(); err != nil {
-		glog.Fatal(err)
+		logger.Fatal("failed to start", "error", err)
 	}
 
+	ll := handler.NewLogLevels(cfg.AdminToken)
+
+	reloader := startReloader(cfg, h, ll)
+
 	e.POST("/sensor1", h.Publish)
 
 	e.GET("/sse/config", h.SubscribeSse)
 	e.GET("/ws/config", h.SubscribeWs)
 
-	handleShutdown(h)
+	e.GET("/history", h.History)
+	e.GET("/status", h.Status)
+
+	e.GET("/config/effective", kc.Effective)
+	e.GET("/config/current", kc.Current)
+
+	e.GET("/shadow", sh.Get)
+	e.PUT("/shadow/reported", sh.UpdateReported)
+	e.GET("/ws/shadow", sh.SubscribeWs)
+
+	admin := e.Group("/admin", ll.Authorize())
+	admin.GET("/log/levels", ll.Get)
+	admin.PUT("/log/levels", ll.Update)
+
+	// The logs are closed last, to log the closing of the others
+	closers = append(closers, logs)
+
+	handleShutdown(h, append([]io.Closer{reloader, store}, closers...)...)
+
+	slog.Info("starting service", "port", cfg.ServicePort)
+
+	if err := e.Start(":" + cfg.ServicePort); err != nil {
+		logger.Fatal("service stopped", "error", err)
+	}
+}
 
-	e.Logger.Fatal(e.Start(":" + cfg.ServicePort))
+// registerMethods registers the RPC methods that admin may call on the kiosk.
+func registerMethods(rs *handler.RPCServer, kc *handler.KioskConfig) {
+	rs.Register(
+		"ping", func(json.RawMessage) (any, error) {
+			return map[string]any{"time": time.Now()}, nil
+		},
+	)
+	rs.Register(
+		"version", func(json.RawMessage) (any, error) {
+			return map[string]any{"version": version}, nil
+		},
+	)
+	rs.Register(
+		"config", func(json.RawMessage) (any, error) {
+			return kc.Config(), nil
+		},
+	)
 }
 
 func frontend(e *echo.Echo, cfg *config.Config) {
@@ -85,7 +212,35 @@ func frontend(e *echo.Echo, cfg *config.Config) {
 	)
 }
 
-func handleShutdown(h *handler.Handler) {
+// startReloader applies the config on SIGHUP or file change, reconnecting to the broker if its settings changed.
+func startReloader(cfg *config.Config, h *handler.Handler, ll *handler.LogLevels) *config.Reloader {
+	r := config.NewReloader(cfg)
+
+	// Reconnecting is the only step that may fail with a valid config, so it is last
+	r.OnReload(
+		func(_, next *config.Config) error {
+			logger.SetDebug(next.Debug)
+			ll.SetToken(next.AdminToken)
+
+			if err := logger.SetPayloadOptions(next.LogPayload); err != nil {
+				return err
+			}
+
+			return logger.SetLevels(next.LogLevels)
+		},
+	)
+	r.OnReload(
+		func(_, next *config.Config) error {
+			return h.Reconfigure(next.ClientOptions())
+		},
+	)
+
+	r.Start()
+
+	return r
+}
+
+func handleShutdown(h *handler.Handler, closers ...io.Closer) {
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
 
@@ -93,6 +248,13 @@ func handleShutdown(h *handler.Handler) {
 		<-sig
 
 		h.Disconnect()
+
+		for _, c := range closers {
+			if err := c.Close(); err != nil {
+				slog.Error("failed to close", "error", err)
+			}
+		}
+
 		os.Exit(0)
 	}()
 }
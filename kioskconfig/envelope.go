@@ -0,0 +1,47 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package kioskconfig
+
+import "encoding/json"
+
+// EnvelopeKind marks a config payload as an envelope, so a raw config with version and data keys is not mistaken
+// for one.
+const EnvelopeKind = "envelope"
+
+// Envelope wraps the config published by admin with its version.
+type Envelope struct {
+	Kind    string          `json:"kind"`
+	Version int64           `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Wrap returns the envelope of the config data.
+func Wrap(version int64, data json.RawMessage) Envelope {
+	return Envelope{Kind: EnvelopeKind, Version: version, Data: data}
+}
+
+// Unwrap returns the envelope of a config payload. A payload without an envelope is returned as the data of
+// version 0.
+func Unwrap(payload []byte) Envelope {
+	var env Envelope
+	if err := json.Unmarshal(payload, &env); err != nil || env.Kind != EnvelopeKind || env.Data == nil {
+		return Envelope{Data: payload}
+	}
+
+	return env
+}
+
+const (
+	AckApplied = "applied"
+	AckFailed  = "failed"
+)
+
+// Ack is published by a kiosk once it has applied, or failed to apply, a config.
+type Ack struct {
+	Version int64  `json:"version"`
+	Topic   string `json:"topic"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
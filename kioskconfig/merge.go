@@ -0,0 +1,150 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package kioskconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrStaleVersion = errors.New("stale config version")
+
+// Source is the latest config received on one of the config topics.
+type Source struct {
+	Topic      string          `json:"topic"`
+	Version    int64           `json:"version,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	ReceivedAt *time.Time      `json:"receivedAt,omitempty"`
+}
+
+// Merger combines the configs received on several topics into the effective config of a kiosk. Topics are given
+// from the lowest to the highest precedence: objects are merged recursively, other values of a higher precedence
+// topic replace those of a lower one, and a null value removes the key.
+type Merger struct {
+	mu      sync.RWMutex
+	sources []Source
+}
+
+func NewMerger(topics ...string) *Merger {
+	m := &Merger{}
+
+	for _, t := range topics {
+		m.sources = append(m.sources, Source{Topic: t})
+	}
+
+	return m
+}
+
+// Topics returns the config topics, from the lowest to the highest precedence.
+func (m *Merger) Topics() []string {
+	topics := make([]string, len(m.sources))
+	for i, s := range m.sources {
+		topics[i] = s.Topic
+	}
+
+	return topics
+}
+
+// Set replaces the config of the topic. A versioned config older than the one already applied is rejected, while
+// configs without a version always replace it.
+func (m *Merger) Set(topic string, version int64, data []byte) error {
+	var v map[string]any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("config must be a JSON object: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.sources {
+		if m.sources[i].Topic == topic {
+			if applied := m.sources[i].Version; version != 0 && version < applied {
+				return fmt.Errorf("%w: %v is older than the applied version %v", ErrStaleVersion, version, applied)
+			}
+
+			now := time.Now()
+
+			m.sources[i].Version = version
+			m.sources[i].Data = data
+			m.sources[i].ReceivedAt = &now
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown config topic %v", topic)
+}
+
+// Restore sets the sources saved by an earlier run. Sources of topics that are no longer subscribed are ignored.
+func (m *Merger) Restore(sources []Source) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, src := range sources {
+		for i := range m.sources {
+			if m.sources[i].Topic == src.Topic {
+				m.sources[i] = src
+			}
+		}
+	}
+}
+
+func (m *Merger) Sources() []Source {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]Source(nil), m.sources...)
+}
+
+// Effective returns the merged config.
+func (m *Merger) Effective() map[string]any {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	effective := make(map[string]any)
+
+	for _, s := range m.sources {
+		if s.Data == nil {
+			continue
+		}
+
+		var v map[string]any
+		if err := json.Unmarshal(s.Data, &v); err != nil {
+			continue
+		}
+
+		merge(effective, v)
+	}
+
+	return effective
+}
+
+func merge(dst, src map[string]any) {
+	for k, v := range src {
+		if v == nil {
+			delete(dst, k)
+
+			continue
+		}
+
+		srcObj, ok := v.(map[string]any)
+		if !ok {
+			dst[k] = v
+
+			continue
+		}
+
+		dstObj, ok := dst[k].(map[string]any)
+		if !ok {
+			dstObj = make(map[string]any)
+			dst[k] = dstObj
+		}
+
+		merge(dstObj, srcObj)
+	}
+}
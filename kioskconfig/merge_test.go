@@ -0,0 +1,164 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package kioskconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const (
+	locationTopic = "location/1/kiosk/config"
+	groupTopic    = "location/1/group/lobby/config"
+	kioskTopic    = "location/1/kiosk/2/config"
+)
+
+// received is a config payload received on a topic.
+type received struct {
+	topic   string
+	payload string
+}
+
+func TestMergerEffective(t *testing.T) {
+	tests := []struct {
+		name     string
+		received []received
+		want     string
+	}{
+		{
+			name: "kiosk over group over location",
+			received: []received{
+				{kioskTopic, `{"brightness": 30}`},
+				{groupTopic, `{"brightness": 60, "volume": 5}`},
+				{locationTopic, `{"brightness": 80, "volume": 10, "lang": "en"}`},
+			},
+			want: `{"brightness": 30, "volume": 5, "lang": "en"}`,
+		},
+		{
+			name: "objects merged recursively",
+			received: []received{
+				{locationTopic, `{"display": {"brightness": 80, "theme": "light"}}`},
+				{kioskTopic, `{"display": {"theme": "dark"}}`},
+			},
+			want: `{"display": {"brightness": 80, "theme": "dark"}}`,
+		},
+		{
+			name: "null removes the key",
+			received: []received{
+				{locationTopic, `{"brightness": 80, "volume": 10}`},
+				{groupTopic, `{"volume": null}`},
+			},
+			want: `{"brightness": 80}`,
+		},
+		{
+			name: "object replaces a value",
+			received: []received{
+				{locationTopic, `{"display": 1}`},
+				{kioskTopic, `{"display": {"theme": "dark"}}`},
+			},
+			want: `{"display": {"theme": "dark"}}`,
+		},
+		{
+			name: "latest config of a topic replaces the previous one",
+			received: []received{
+				{locationTopic, `{"brightness": 80, "volume": 10}`},
+				{locationTopic, `{"brightness": 70}`},
+			},
+			want: `{"brightness": 70}`,
+		},
+		{
+			name: "stale envelope dropped",
+			received: []received{
+				{locationTopic, `{"kind": "envelope", "version": 2, "data": {"brightness": 70}}`},
+				{locationTopic, `{"kind": "envelope", "version": 1, "data": {"brightness": 80}}`},
+			},
+			want: `{"brightness": 70}`,
+		},
+		{
+			name: "raw config replaces a versioned one",
+			received: []received{
+				{locationTopic, `{"kind": "envelope", "version": 2, "data": {"brightness": 70}}`},
+				{locationTopic, `{"brightness": 80}`},
+			},
+			want: `{"brightness": 80}`,
+		},
+		{
+			name:     "nothing received",
+			received: nil,
+			want:     `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				m := NewMerger(locationTopic, groupTopic, kioskTopic)
+
+				for _, r := range tt.received {
+					env := Unwrap([]byte(r.payload))
+
+					// Stale configs are rejected, and must not change the effective config
+					if err := m.Set(r.topic, env.Version, env.Data); err != nil && !errors.Is(err, ErrStaleVersion) {
+						t.Fatal(err)
+					}
+				}
+
+				var want map[string]any
+				if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+					t.Fatal(err)
+				}
+
+				if got := m.Effective(); !reflect.DeepEqual(got, want) {
+					t.Errorf("effective config %v, want %v", got, want)
+				}
+			},
+		)
+	}
+}
+
+func TestMergerSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied int64
+		topic   string
+		version int64
+		data    string
+		wantErr bool
+		stale   bool
+	}{
+		{name: "newer version", applied: 1, topic: kioskTopic, version: 2, data: `{}`},
+		{name: "same version", applied: 2, topic: kioskTopic, version: 2, data: `{}`},
+		{name: "older version", applied: 2, topic: kioskTopic, version: 1, data: `{}`, wantErr: true, stale: true},
+		{name: "without version", applied: 2, topic: kioskTopic, version: 0, data: `{}`},
+		{name: "first version", topic: kioskTopic, version: 1, data: `{}`},
+		{name: "unknown topic", topic: "location/1/kiosk/3/config", version: 1, data: `{}`, wantErr: true},
+		{name: "not an object", topic: kioskTopic, version: 1, data: `[1]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				m := NewMerger(locationTopic, groupTopic, kioskTopic)
+
+				if tt.applied != 0 {
+					if err := m.Set(kioskTopic, tt.applied, []byte(`{}`)); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				err := m.Set(tt.topic, tt.version, []byte(tt.data))
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("error %v, want error %v", err, tt.wantErr)
+				}
+
+				if errors.Is(err, ErrStaleVersion) != tt.stale {
+					t.Errorf("error %v, want stale %v", err, tt.stale)
+				}
+			},
+		)
+	}
+}
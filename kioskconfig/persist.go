@@ -0,0 +1,74 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package kioskconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Applied is the config last applied by a kiosk, as kept on local disk.
+type Applied struct {
+	Sources []Source  `json:"sources"`
+	SavedAt time.Time `json:"savedAt"`
+}
+
+// Load reads the applied config from the file. A missing file returns an empty config.
+func Load(path string) (*Applied, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Applied{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var a Applied
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// Save writes the applied config to the file. It writes to a temporary file first, so a crash never leaves a partial
+// config behind.
+func Save(path string, sources []Source) error {
+	data, err := json.MarshalIndent(Applied{Sources: sources, SavedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
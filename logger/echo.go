@@ -0,0 +1,95 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	glog "github.com/labstack/gommon/log"
+)
+
+// Echo routes the logs of Echo and its HTTP server to the http subsystem, and logs every request.
+func Echo(e *echo.Echo) {
+	l := glog.New("echo")
+	l.SetHeader("${level}")
+	l.SetOutput(echoWriter{})
+
+	// The level is filtered by slog
+	l.SetLevel(glog.DEBUG)
+
+	e.Logger = l
+	e.StdLogger = slog.NewLogLogger(For(SubsystemHTTP).Handler(), slog.LevelError)
+	e.HideBanner = true
+	e.HidePort = true
+
+	e.Use(
+		middleware.RequestLoggerWithConfig(
+			middleware.RequestLoggerConfig{
+				LogMethod:     true,
+				LogURI:        true,
+				LogStatus:     true,
+				LogLatency:    true,
+				LogRemoteIP:   true,
+				LogError:      true,
+				HandleError:   true,
+				LogValuesFunc: logRequest,
+			},
+		),
+	)
+}
+
+func logRequest(c echo.Context, v middleware.RequestLoggerValues) error {
+	lvl := slog.LevelInfo
+	if v.Status >= 500 {
+		lvl = slog.LevelError
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", v.Method),
+		slog.String("uri", v.URI),
+		slog.Int("status", v.Status),
+		slog.Duration("latency", v.Latency),
+		slog.String("remote_ip", v.RemoteIP),
+	}
+
+	if v.Error != nil {
+		attrs = append(attrs, slog.String("error", v.Error.Error()))
+	}
+
+	For(SubsystemHTTP).LogAttrs(c.Request().Context(), lvl, "request", attrs...)
+
+	return nil
+}
+
+// echoWriter writes the lines of Echo's logger, prefixed by their level, to the http subsystem.
+type echoWriter struct{}
+
+func (echoWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		lvl, msg := slog.LevelInfo, string(line)
+
+		if prefix, rest, ok := strings.Cut(msg, " "); ok {
+			switch prefix {
+			case "DEBUG":
+				lvl, msg = slog.LevelDebug, rest
+			case "INFO", "-":
+				msg = rest
+			case "WARN":
+				lvl, msg = slog.LevelWarn, rest
+			case "ERROR":
+				lvl, msg = slog.LevelError, rest
+			}
+		}
+
+		For(SubsystemHTTP).Log(context.Background(), lvl, strings.TrimSpace(msg))
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// The defaults of the file options.
+const (
+	DefaultFileMaxSize        = 100
+	DefaultFileRotateInterval = 24 * time.Hour
+	DefaultFileMaxBackups     = 10
+	DefaultFileMaxAge         = 7 * 24 * time.Hour
+)
+
+// FileOptions sets the file that the logs are written to, in addition to the output. Without a path, the logs are
+// only written to the output.
+type FileOptions struct {
+	Path string
+
+	// MaxSize rotates the file once it reaches the size in megabytes
+	MaxSize int
+
+	// RotateInterval rotates the file at every multiple of the interval, e.g. at midnight UTC with 24h. 0 only
+	// rotates by size.
+	RotateInterval time.Duration
+
+	// MaxBackups and MaxAge remove the rotated files beyond the count, and older than the age rounded up to days.
+	// 0 keeps them.
+	MaxBackups int
+	MaxAge     time.Duration
+
+	// Compress compresses the rotated files with gzip
+	Compress bool
+}
+
+// file writes the logs to a file rotated by size and time.
+type file struct {
+	*lumberjack.Logger
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// openFile opens the file upfront, so an invalid path fails at startup.
+func openFile(opts FileOptions) (*file, error) {
+	f := &file{
+		Logger: &lumberjack.Logger{
+			Filename:   opts.Path,
+			MaxSize:    opts.MaxSize,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     days(opts.MaxAge),
+			Compress:   opts.Compress,
+		},
+		done: make(chan struct{}),
+	}
+
+	if _, err := f.Write(nil); err != nil {
+		return nil, err
+	}
+
+	if opts.RotateInterval > 0 {
+		f.wg.Add(1)
+
+		go f.rotate(opts.RotateInterval)
+	}
+
+	return f, nil
+}
+
+// days rounds the age up to days, which is the unit of lumberjack.
+func days(age time.Duration) int {
+	const day = 24 * time.Hour
+
+	return int((age + day - 1) / day)
+}
+
+func (f *file) rotate(interval time.Duration) {
+	defer f.wg.Done()
+
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(interval).Add(interval).Sub(now))
+
+		select {
+		case <-f.done:
+			timer.Stop()
+
+			return
+		case <-timer.C:
+			if err := f.Rotate(); err != nil {
+				slog.Error("failed to rotate log file", "file", f.Filename, "error", err)
+			}
+		}
+	}
+}
+
+// Close stops the rotation and closes the file.
+func (f *file) Close() error {
+	close(f.done)
+	f.wg.Wait()
+
+	return f.Logger.Close()
+}
+
+// teeWriter writes to every writer, even if a writer fails, so the file is still written if the output is closed.
+type teeWriter []io.Writer
+
+func (t teeWriter) Write(p []byte) (int, error) {
+	var err error
+
+	for _, w := range t {
+		if _, werr := w.Write(p); werr != nil && err == nil {
+			err = werr
+		}
+	}
+
+	return len(p), err
+}
+
+// nopCloser is returned by Init without a file.
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// The subsystems with a log level of their own. Other logs are at the level set by SetDebug.
+const (
+	// SubsystemMQTT logs within the paho libraries
+	SubsystemMQTT = "mqtt"
+
+	// SubsystemClient logs the broker connections
+	SubsystemClient = "client"
+
+	// SubsystemHandler logs the handling of messages and endpoints
+	SubsystemHandler = "handler"
+
+	// SubsystemWatcher logs the relay of messages to the WebSocket and SSE clients
+	SubsystemWatcher = "watcher"
+
+	// SubsystemHTTP logs Echo, its HTTP server and every request
+	SubsystemHTTP = "http"
+)
+
+// subsystem follows the shared level, unless its level is set.
+type subsystem struct {
+	level slog.LevelVar
+	set   atomic.Bool
+}
+
+func (s *subsystem) Level() slog.Level {
+	if s.set.Load() {
+		return s.level.Level()
+	}
+
+	return level.Level()
+}
+
+var subsystems = map[string]*subsystem{
+	SubsystemMQTT:    {},
+	SubsystemClient:  {},
+	SubsystemHandler: {},
+	SubsystemWatcher: {},
+	SubsystemHTTP:    {},
+}
+
+// handlers are the handlers of the subsystems, built by Init from the default logger.
+var handlers atomic.Pointer[map[string]slog.Handler]
+
+// Subsystems returns the names of the subsystems, sorted.
+func Subsystems() []string {
+	return slices.Sorted(maps.Keys(subsystems))
+}
+
+// For returns the logger of the subsystem, which logs with a subsystem attribute at the level of the subsystem. It
+// may be called before Init, e.g. for a package variable, since its logs go to the logger set by Init. An unknown
+// subsystem returns the default logger.
+func For(name string) *slog.Logger {
+	if _, ok := subsystems[name]; !ok {
+		return slog.Default()
+	}
+
+	return slog.New(subsystemHandler{name: name})
+}
+
+// subsystemHandler passes the records to the current handler of the subsystem. Its attributes and groups are bound
+// to the handler current at the time.
+type subsystemHandler struct {
+	name string
+}
+
+func (h subsystemHandler) handler() slog.Handler {
+	if m := handlers.Load(); m != nil {
+		return (*m)[h.name]
+	}
+
+	return newSubsystemHandler(slog.Default().Handler(), h.name)
+}
+
+func (h subsystemHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= subsystems[h.name].Level()
+}
+
+func (h subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler().Handle(ctx, r)
+}
+
+func (h subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.handler().WithAttrs(attrs)
+}
+
+func (h subsystemHandler) WithGroup(name string) slog.Handler {
+	return h.handler().WithGroup(name)
+}
+
+func newSubsystemHandler(base slog.Handler, name string) slog.Handler {
+	return levelHandler{Handler: base, level: subsystems[name]}.WithAttrs([]slog.Attr{slog.String("subsystem", name)})
+}
+
+// initHandlers builds the handlers of the subsystems from the default logger.
+func initHandlers() {
+	m := make(map[string]slog.Handler, len(subsystems))
+	for name := range subsystems {
+		m[name] = newSubsystemHandler(slog.Default().Handler(), name)
+	}
+
+	handlers.Store(&m)
+}
+
+// levelHandler filters the records by the level of a subsystem, instead of the level of its handler.
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h levelHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+// Level returns the shared level.
+func Level() slog.Level {
+	return level.Level()
+}
+
+// SubsystemLevels returns the levels of the subsystems whose level is set.
+func SubsystemLevels() map[string]slog.Level {
+	levels := make(map[string]slog.Level)
+
+	for name, s := range subsystems {
+		if s.set.Load() {
+			levels[name] = s.level.Level()
+		}
+	}
+
+	return levels
+}
+
+// SetLevel sets the level of the subsystem, until the next SetLevels.
+func SetLevel(name string, l slog.Level) error {
+	s, ok := subsystems[name]
+	if !ok {
+		return unknownSubsystem(name)
+	}
+
+	s.level.Set(l)
+	s.set.Store(true)
+
+	return nil
+}
+
+// ResetLevel makes the subsystem follow the shared level again.
+func ResetLevel(name string) error {
+	s, ok := subsystems[name]
+	if !ok {
+		return unknownSubsystem(name)
+	}
+
+	s.set.Store(false)
+
+	return nil
+}
+
+// SetLevels sets the levels of the subsystems, e.g. when the config is reloaded. The other subsystems follow the
+// shared level.
+func SetLevels(levels map[string]slog.Level) error {
+	for name := range levels {
+		if _, ok := subsystems[name]; !ok {
+			return unknownSubsystem(name)
+		}
+	}
+
+	for name, s := range subsystems {
+		if l, ok := levels[name]; ok {
+			s.level.Set(l)
+			s.set.Store(true)
+
+			continue
+		}
+
+		s.set.Store(false)
+	}
+
+	return nil
+}
+
+// ParseLevels parses comma-separated levels of subsystems, such as mqtt=debug,http=warn.
+func ParseLevels(s string) (map[string]slog.Level, error) {
+	levels := make(map[string]slog.Level)
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid log level %q (subsystem=level)", item)
+		}
+
+		name = strings.TrimSpace(name)
+		if _, ok := subsystems[name]; !ok {
+			return nil, unknownSubsystem(name)
+		}
+
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+			return nil, fmt.Errorf("invalid log level of %v: %w", name, err)
+		}
+
+		levels[name] = l
+	}
+
+	return levels, nil
+}
+
+func unknownSubsystem(name string) error {
+	return fmt.Errorf("unknown log subsystem %q (%v)", name, strings.Join(Subsystems(), ", "))
+}
@@ -2,96 +2,159 @@
 // Use of this source code is governed by an MIT-style
 // license that can be found in the LICENSE file.
 
+// Package logger sets up structured logging with log/slog, and routes the logs of paho and Echo through it.
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
-	glog "github.com/labstack/gommon/log"
 )
 
-type Fatal struct{}
+// The formats of the logs.
+const (
+	FormatLogfmt = "logfmt"
+	FormatJSON   = "json"
+)
 
-func (f Fatal) Println(v ...interface{}) {
-	glog.Fatal(v...)
-}
+// level is shared by every logger, so it can be changed at runtime.
+var level = new(slog.LevelVar)
 
-func (f Fatal) Printf(format string, v ...interface{}) {
-	glog.Fatalf(format, v...)
-}
+type Options struct {
+	// Format is logfmt (default) or json
+	Format string
+	Debug  bool
 
-type Panic struct{}
+	// Levels are the levels of the subsystems, which otherwise follow Debug
+	Levels map[string]slog.Level
 
-func (p Panic) Println(v ...interface{}) {
-	glog.Panic(v...)
-}
+	// Output defaults to stderr
+	Output io.Writer
 
-func (p Panic) Printf(format string, v ...interface{}) {
-	glog.Panicf(format, v...)
-}
+	// File is written in addition to the output, if set
+	File FileOptions
 
-type Error struct{}
+	// Payload sets how the payloads of messages are logged
+	Payload PayloadOptions
 
-func (e Error) Println(v ...interface{}) {
-	glog.Error(v...)
+	// Attrs are added to every log, e.g. the location and kiosk of the service
+	Attrs []any
 }
 
-func (e Error) Printf(format string, v ...interface{}) {
-	glog.Errorf(format, v...)
+// NewHandler returns a handler of the format, at the shared level.
+func NewHandler(format string, w io.Writer) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case "", FormatLogfmt:
+		return slog.NewTextHandler(w, opts), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("unsupported log format %q (logfmt or json)", format)
+	}
 }
 
-type Warn struct{}
+// Init sets the default logger of slog, and routes the logs of paho to it. The returned closer closes the log file,
+// and should be closed last.
+func Init(opts Options) (io.Closer, error) {
+	if opts.Output == nil {
+		opts.Output = os.Stderr
+	}
 
-func (w Warn) Println(v ...interface{}) {
-	glog.Warn(v...)
-}
+	if err := SetLevels(opts.Levels); err != nil {
+		return nil, err
+	}
 
-func (w Warn) Printf(format string, v ...interface{}) {
-	glog.Warnf(format, v...)
-}
+	if err := SetPayloadOptions(opts.Payload); err != nil {
+		return nil, err
+	}
 
-type Info struct{}
+	var closer io.Closer = nopCloser{}
 
-func (i Info) Println(v ...interface{}) {
-	glog.Info(v...)
-}
+	w := opts.Output
 
-func (i Info) Printf(format string, v ...interface{}) {
-	glog.Infof(format, v...)
-}
+	if opts.File.Path != "" {
+		f, err := openFile(opts.File)
+		if err != nil {
+			return nil, err
+		}
 
-type Debug struct{}
+		w = teeWriter{opts.Output, f}
+		closer = f
+	}
 
-func (d Debug) Println(v ...interface{}) {
-	glog.Debug(v...)
-}
+	h, err := NewHandler(opts.Format, w)
+	if err != nil {
+		_ = closer.Close()
 
-func (d Debug) Printf(format string, v ...interface{}) {
-	glog.Debugf(format, v...)
+		return nil, err
+	}
+
+	slog.SetDefault(slog.New(h).With(opts.Attrs...))
+	initHandlers()
+
+	mqtt.ERROR = Paho{Level: slog.LevelError}
+	mqtt.CRITICAL = Paho{Level: slog.LevelError}
+	// The warnings of paho are silenced by the level of the mqtt subsystem, e.g. mqtt=error
+	mqtt.WARN = Paho{Level: slog.LevelWarn}
+	mqtt.DEBUG = Paho{Level: slog.LevelDebug}
+
+	SetDebug(opts.Debug)
+
+	return closer, nil
 }
 
-type Plain struct{}
+// SetDebug switches the debug logs on or off, e.g. when the config is reloaded.
+func SetDebug(debug bool) {
+	if debug {
+		level.Set(slog.LevelDebug)
+
+		return
+	}
 
-func (p Plain) Println(v ...interface{}) {
-	glog.Print(v...)
+	level.Set(slog.LevelInfo)
 }
 
-func (p Plain) Printf(format string, v ...interface{}) {
-	glog.Printf(format, v...)
+// Fatal logs the error and exits.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
 
-func Init() {
-	glog.SetHeader("${time_rfc3339} ${level} ${short_file}:${line}")
-	glog.EnableColor()
+// Paho adapts a logger to the loggers of both paho libraries, logging at the level. Without a logger, the logger of
+// the mqtt subsystem is used.
+type Paho struct {
+	Logger *slog.Logger
+	Level  slog.Level
+}
 
-	mqtt.ERROR = Error{}
-	mqtt.CRITICAL = Error{}
-	mqtt.WARN = Warn{}
+func (p Paho) logger() *slog.Logger {
+	if p.Logger == nil {
+		return For(SubsystemMQTT)
+	}
+
+	return p.Logger
+}
 
-	if os.Getenv("DEBUG") == "1" {
-		glog.SetLevel(glog.DEBUG)
+func (p Paho) log(msg string) {
+	p.logger().Log(context.Background(), p.Level, strings.TrimSpace(msg))
+}
+
+// Println and Printf only format the message if the level is enabled, as paho logs a lot at debug level.
+func (p Paho) Println(v ...interface{}) {
+	if p.logger().Enabled(context.Background(), p.Level) {
+		p.log(fmt.Sprintln(v...))
+	}
+}
 
-		mqtt.DEBUG = Debug{}
+func (p Paho) Printf(format string, v ...interface{}) {
+	if p.logger().Enabled(context.Background(), p.Level) {
+		p.log(fmt.Sprintf(format, v...))
 	}
 }
@@ -0,0 +1,268 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"go-mqtt-demo/secret"
+)
+
+// The modes of logging the payloads of messages.
+const (
+	// PayloadFull logs the whole payload
+	PayloadFull = "full"
+
+	// PayloadTruncate logs the payload up to MaxBytes
+	PayloadTruncate = "truncate"
+
+	// PayloadHash logs the SHA-256 of the payload, to match payloads without logging them
+	PayloadHash = "hash"
+
+	// PayloadOff only logs the size of the payload
+	PayloadOff = "off"
+)
+
+const DefaultPayloadMaxBytes = 256
+
+// PayloadOptions sets how the payloads of messages are logged.
+type PayloadOptions struct {
+	// Mode defaults to truncate
+	Mode     string
+	MaxBytes int
+
+	// Redact are the JSON paths of the fields replaced by [redacted] in JSON payloads, e.g. $.user.password or
+	// $.readings[*].serial, with * matching any field or item
+	Redact []string
+}
+
+// payloadLogger formats the payloads with the options.
+type payloadLogger struct {
+	mode     string
+	maxBytes int
+	redact   [][]string
+}
+
+var payloads atomic.Pointer[payloadLogger]
+
+func newPayloadLogger(opts PayloadOptions) (*payloadLogger, error) {
+	p := &payloadLogger{mode: opts.Mode, maxBytes: opts.MaxBytes}
+
+	switch p.mode {
+	case "":
+		p.mode = PayloadTruncate
+	case PayloadFull, PayloadTruncate, PayloadHash, PayloadOff:
+	default:
+		return nil, fmt.Errorf("unsupported payload log mode %q (full, truncate, hash or off)", opts.Mode)
+	}
+
+	if p.maxBytes < 0 {
+		return nil, errors.New("payload log max bytes must not be negative")
+	}
+
+	if p.maxBytes == 0 {
+		p.maxBytes = DefaultPayloadMaxBytes
+	}
+
+	for _, path := range opts.Redact {
+		segments, err := parseJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+
+		p.redact = append(p.redact, segments)
+	}
+
+	return p, nil
+}
+
+// Validate checks the mode and the JSON paths of the options.
+func (o PayloadOptions) Validate() error {
+	_, err := newPayloadLogger(o)
+
+	return err
+}
+
+// SetPayloadOptions sets how the payloads are logged, e.g. when the config is reloaded.
+func SetPayloadOptions(opts PayloadOptions) error {
+	p, err := newPayloadLogger(opts)
+	if err != nil {
+		return err
+	}
+
+	payloads.Store(p)
+
+	return nil
+}
+
+// PayloadAttrs returns the log attributes of a payload: its size, and the payload as set by SetPayloadOptions. The
+// payload is only formatted if the log is written.
+func PayloadAttrs(payload []byte) []any {
+	return []any{"payload_size", len(payload), slog.Any("payload", payloadValue(payload))}
+}
+
+type payloadValue []byte
+
+func (v payloadValue) LogValue() slog.Value {
+	p := payloads.Load()
+	if p == nil {
+		p = &payloadLogger{mode: PayloadTruncate, maxBytes: DefaultPayloadMaxBytes}
+	}
+
+	switch p.mode {
+	case PayloadOff:
+		// An empty group is omitted
+		return slog.GroupValue()
+	case PayloadHash:
+		sum := sha256.Sum256(v)
+
+		return slog.StringValue("sha256:" + hex.EncodeToString(sum[:]))
+	}
+
+	payload := p.redactJSON(v)
+
+	if p.mode == PayloadTruncate && len(payload) > p.maxBytes {
+		return slog.StringValue(string(truncate(payload, p.maxBytes)) + "...")
+	}
+
+	return slog.StringValue(string(payload))
+}
+
+// redactJSON replaces the fields of the JSON paths. A payload that is not JSON is returned as is.
+func (p *payloadLogger) redactJSON(payload []byte) []byte {
+	if len(p.redact) == 0 {
+		return payload
+	}
+
+	var v any
+
+	d := json.NewDecoder(bytes.NewReader(payload))
+	d.UseNumber()
+
+	if err := d.Decode(&v); err != nil {
+		return payload
+	}
+
+	for _, segments := range p.redact {
+		redact(v, segments)
+	}
+
+	var redacted bytes.Buffer
+
+	e := json.NewEncoder(&redacted)
+	e.SetEscapeHTML(false)
+
+	if err := e.Encode(v); err != nil {
+		return payload
+	}
+
+	return bytes.TrimSuffix(redacted.Bytes(), []byte("\n"))
+}
+
+func redact(v any, segments []string) {
+	segment, rest := segments[0], segments[1:]
+
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if segment != "*" && segment != k {
+				continue
+			}
+
+			if len(rest) == 0 {
+				v[k] = secret.Redacted
+
+				continue
+			}
+
+			redact(child, rest)
+		}
+	case []any:
+		for i, child := range v {
+			if segment != "*" && segment != strconv.Itoa(i) {
+				continue
+			}
+
+			if len(rest) == 0 {
+				v[i] = secret.Redacted
+
+				continue
+			}
+
+			redact(child, rest)
+		}
+	}
+}
+
+// truncate cuts the payload to at most n bytes, without splitting a UTF-8 character.
+func truncate(payload []byte, n int) []byte {
+	if n >= len(payload) {
+		return payload
+	}
+
+	for n > 0 && !utf8.RuneStart(payload[n]) {
+		n--
+	}
+
+	return payload[:n]
+}
+
+// parseJSONPath parses a JSON path of fields and items, such as $.readings[*].serial or readings.0.serial, into its
+// segments. The leading $ is optional.
+func parseJSONPath(path string) ([]string, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if s != "" && s[0] != '.' && s[0] != '[' {
+		s = "." + s
+	}
+
+	var segments []string
+
+	for s != "" {
+		var segment string
+
+		switch s[0] {
+		case '.':
+			s = s[1:]
+
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+
+			segment, s = s[:end], s[end:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid json path %q: missing ]", path)
+			}
+
+			segment, s = strings.Trim(s[1:end], `'"`), s[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid json path %q", path)
+		}
+
+		if segment == "" {
+			return nil, fmt.Errorf("invalid json path %q: empty field", path)
+		}
+
+		segments = append(segments, segment)
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid json path %q", path)
+	}
+
+	return segments, nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{path: "$.user.password", want: []string{"user", "password"}},
+		{path: "user.password", want: []string{"user", "password"}},
+		{path: "$.readings[*].serial", want: []string{"readings", "*", "serial"}},
+		{path: "$.readings[0].serial", want: []string{"readings", "0", "serial"}},
+		{path: "readings.0.serial", want: []string{"readings", "0", "serial"}},
+		{path: "$[0].serial", want: []string{"0", "serial"}},
+		{path: "$['a.b']", want: []string{"a.b"}},
+		{path: " $.token ", want: []string{"token"}},
+		{path: "", wantErr: true},
+		{path: "$", wantErr: true},
+		{path: "$.user..password", wantErr: true},
+		{path: "$.readings[0", wantErr: true},
+		{path: "$.readings[]", wantErr: true},
+		{path: "$.readings[0]serial", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.path, func(t *testing.T) {
+				got, err := parseJSONPath(tt.path)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("error %v, want error %v", err, tt.wantErr)
+				}
+
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("segments %q, want %q", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestPayloadValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    PayloadOptions
+		payload string
+		want    string
+	}{
+		{
+			name:    "field redacted",
+			opts:    PayloadOptions{Mode: PayloadFull, Redact: []string{"$.user.password"}},
+			payload: `{"user":{"name":"a","password":"p"}}`,
+			want:    `{"user":{"name":"a","password":"[redacted]"}}`,
+		},
+		{
+			name:    "item of an array index redacted",
+			opts:    PayloadOptions{Mode: PayloadFull, Redact: []string{"$.readings[1].serial"}},
+			payload: `{"readings":[{"serial":"s1"},{"serial":"s2"}]}`,
+			want:    `{"readings":[{"serial":"s1"},{"serial":"[redacted]"}]}`,
+		},
+		{
+			name:    "items of all indexes redacted",
+			opts:    PayloadOptions{Mode: PayloadFull, Redact: []string{"$.readings[*].serial"}},
+			payload: `{"readings":[{"serial":"s1"},{"serial":"s2"}]}`,
+			want:    `{"readings":[{"serial":"[redacted]"},{"serial":"[redacted]"}]}`,
+		},
+		{
+			name:    "whole array item redacted",
+			opts:    PayloadOptions{Mode: PayloadFull, Redact: []string{"$[0]"}},
+			payload: `["s1","s2"]`,
+			want:    `["[redacted]","s2"]`,
+		},
+		{
+			name:    "missing field",
+			opts:    PayloadOptions{Mode: PayloadFull, Redact: []string{"$.user.token"}},
+			payload: `{"user":{"name":"a"}}`,
+			want:    `{"user":{"name":"a"}}`,
+		},
+		{
+			name:    "index out of range",
+			opts:    PayloadOptions{Mode: PayloadFull, Redact: []string{"$.readings[5].serial"}},
+			payload: `{"readings":[{"serial":"s1"}]}`,
+			want:    `{"readings":[{"serial":"s1"}]}`,
+		},
+		{
+			name:    "path through a value",
+			opts:    PayloadOptions{Mode: PayloadFull, Redact: []string{"$.user.name.first"}},
+			payload: `{"user":{"name":"a"}}`,
+			want:    `{"user":{"name":"a"}}`,
+		},
+		{
+			name:    "numbers kept as is",
+			opts:    PayloadOptions{Mode: PayloadFull, Redact: []string{"$.serial"}},
+			payload: `{"serial":"s1","t":21.50}`,
+			want:    `{"serial":"[redacted]","t":21.50}`,
+		},
+		{
+			name:    "not json",
+			opts:    PayloadOptions{Mode: PayloadFull, Redact: []string{"$.serial"}},
+			payload: `serial=s1`,
+			want:    `serial=s1`,
+		},
+		{
+			name:    "truncated",
+			opts:    PayloadOptions{Mode: PayloadTruncate, MaxBytes: 4},
+			payload: `abcde`,
+			want:    `abcd...`,
+		},
+		{
+			name:    "at the boundary",
+			opts:    PayloadOptions{Mode: PayloadTruncate, MaxBytes: 5},
+			payload: `abcde`,
+			want:    `abcde`,
+		},
+		{
+			name:    "character not split",
+			opts:    PayloadOptions{Mode: PayloadTruncate, MaxBytes: 4},
+			payload: `abcé`,
+			want:    `abc...`,
+		},
+		{
+			name:    "redacted before truncated",
+			opts:    PayloadOptions{Mode: PayloadTruncate, MaxBytes: 25, Redact: []string{"$.password"}},
+			payload: `{"password":"secret-value"}`,
+			want:    `{"password":"[redacted]"}`,
+		},
+		{
+			name:    "full",
+			opts:    PayloadOptions{Mode: PayloadFull, MaxBytes: 4},
+			payload: `abcde`,
+			want:    `abcde`,
+		},
+		{
+			name:    "hash",
+			opts:    PayloadOptions{Mode: PayloadHash},
+			payload: `abc`,
+			want:    "sha256:ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		},
+	}
+
+	t.Cleanup(
+		func() {
+			payloads.Store(nil)
+		},
+	)
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if err := SetPayloadOptions(tt.opts); err != nil {
+					t.Fatal(err)
+				}
+
+				if got := payloadValue(tt.payload).LogValue().String(); got != tt.want {
+					t.Errorf("payload %s, want %s", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		n       int
+		want    string
+	}{
+		{"shorter", "abc", 5, "abc"},
+		{"at the boundary", "abc", 3, "abc"},
+		{"longer", "abcd", 3, "abc"},
+		{"nothing", "abc", 0, ""},
+		{"before a multibyte character", "abé", 2, "ab"},
+		{"within a multibyte character", "abé", 3, "ab"},
+		{"after a multibyte character", "abéd", 4, "abé"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := string(truncate([]byte(tt.payload), tt.n)); got != tt.want {
+					t.Errorf("truncate(%q, %v) = %q, want %q", tt.payload, tt.n, got, tt.want)
+				}
+			},
+		)
+	}
+}
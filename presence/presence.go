@@ -0,0 +1,216 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package presence
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"go-mqtt-demo/client"
+	"go-mqtt-demo/logger"
+	"go-mqtt-demo/topic"
+)
+
+// log logs at the level of the handler subsystem.
+var log = logger.For(logger.SubsystemHandler)
+
+// Kiosk is the presence of a kiosk. LastSeen is the last time a live message was received from it. Removed is only
+// set on the update of a kiosk that was removed from the table.
+type Kiosk struct {
+	LocationId string    `json:"locationId"`
+	KioskId    string    `json:"kioskId"`
+	Online     bool      `json:"online"`
+	LastSeen   time.Time `json:"lastSeen"`
+	ChangedAt  time.Time `json:"changedAt"`
+	Removed    bool      `json:"removed,omitempty"`
+}
+
+// Table tracks the presence of kiosks from their status and sensor messages.
+type Table struct {
+	mu          sync.RWMutex
+	kiosks      map[string]*Kiosk
+	subscribers map[chan Kiosk]struct{}
+}
+
+func NewTable() *Table {
+	return &Table{
+		kiosks:      make(map[string]*Kiosk),
+		subscribers: make(map[chan Kiosk]struct{}),
+	}
+}
+
+// HandleStatus updates the presence from a retained status message, or a Last Will. A cleared status, e.g. when the
+// kiosk is deleted from the fleet, removes the kiosk.
+func (t *Table) HandleStatus(msg *client.Message) {
+	if len(msg.Payload) == 0 {
+		t.remove(msg.Topic)
+
+		return
+	}
+
+	var status client.Status
+	if err := json.Unmarshal(msg.Payload, &status); err != nil {
+		log.Error("invalid status", "topic", msg.Topic, "error", err)
+
+		return
+	}
+
+	t.update(msg.Topic, status.Status == client.StatusOnline)
+}
+
+// Touch updates the last time the known kiosk that sent the message was seen. Only its status changes whether it is
+// online, since a message may be queued by the broker while the kiosk is offline. Retained messages are skipped, as
+// they may have been sent long ago.
+func (t *Table) Touch(msg *client.Message) {
+	if msg.Retained {
+		return
+	}
+
+	locationId, kioskId, ok := topic.ParseKiosk(msg.Topic)
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+
+	k, ok := t.kiosks[locationId+"/"+kioskId]
+	if !ok {
+		t.mu.Unlock()
+
+		return
+	}
+
+	k.LastSeen = time.Now()
+	update := *k
+
+	t.mu.Unlock()
+
+	t.publish(update)
+}
+
+func (t *Table) update(kioskTopic string, online bool) {
+	locationId, kioskId, ok := topic.ParseKiosk(kioskTopic)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+
+	t.mu.Lock()
+
+	k, ok := t.kiosks[locationId+"/"+kioskId]
+	if !ok {
+		k = &Kiosk{LocationId: locationId, KioskId: kioskId}
+		t.kiosks[locationId+"/"+kioskId] = k
+	}
+
+	if !ok || k.Online != online {
+		k.Online = online
+		k.ChangedAt = now
+	}
+
+	if online {
+		k.LastSeen = now
+	}
+
+	update := *k
+
+	t.mu.Unlock()
+
+	t.publish(update)
+}
+
+func (t *Table) remove(kioskTopic string) {
+	locationId, kioskId, ok := topic.ParseKiosk(kioskTopic)
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+
+	k, ok := t.kiosks[locationId+"/"+kioskId]
+	if !ok {
+		t.mu.Unlock()
+
+		return
+	}
+
+	delete(t.kiosks, locationId+"/"+kioskId)
+
+	update := *k
+	update.Online = false
+	update.ChangedAt = time.Now()
+	update.Removed = true
+
+	t.mu.Unlock()
+
+	t.publish(update)
+}
+
+func (t *Table) publish(k Kiosk) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for sub := range t.subscribers {
+		select {
+		case sub <- k:
+		default:
+			log.Error("presence subscriber is too slow, dropping update", "kiosk_id", k.KioskId)
+		}
+	}
+}
+
+// Get returns the presence of a kiosk, if known.
+func (t *Table) Get(locationId, kioskId string) (Kiosk, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	k, ok := t.kiosks[locationId+"/"+kioskId]
+	if !ok {
+		return Kiosk{}, false
+	}
+
+	return *k, true
+}
+
+// List returns the presence of all known kiosks, ordered by location and kiosk ID.
+func (t *Table) List() []Kiosk {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	kiosks := make([]Kiosk, 0, len(t.kiosks))
+	for _, k := range t.kiosks {
+		kiosks = append(kiosks, *k)
+	}
+
+	sort.Slice(
+		kiosks, func(i, j int) bool {
+			if kiosks[i].LocationId != kiosks[j].LocationId {
+				return kiosks[i].LocationId < kiosks[j].LocationId
+			}
+
+			return kiosks[i].KioskId < kiosks[j].KioskId
+		},
+	)
+
+	return kiosks
+}
+
+// Subscribe returns a chan of presence changes. The returned func must be called to unsubscribe.
+func (t *Table) Subscribe() (<-chan Kiosk, func()) {
+	sub := make(chan Kiosk, client.DefaultBufferSize)
+
+	t.mu.Lock()
+	t.subscribers[sub] = struct{}{}
+	t.mu.Unlock()
+
+	return sub, func() {
+		t.mu.Lock()
+		delete(t.subscribers, sub)
+		t.mu.Unlock()
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package rollout
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go-mqtt-demo/client"
+	"go-mqtt-demo/kioskconfig"
+	"go-mqtt-demo/logger"
+	"go-mqtt-demo/topic"
+)
+
+// log logs at the level of the handler subsystem.
+var log = logger.For(logger.SubsystemHandler)
+
+const (
+	StatusPending = "pending"
+
+	// MaxRollouts is how many of the latest rollouts are tracked.
+	MaxRollouts = 100
+)
+
+type KioskStatus struct {
+	KioskId   string     `json:"kioskId"`
+	Status    string     `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// Rollout is the apply status of a published config version across its target kiosks.
+type Rollout struct {
+	Version     int64                   `json:"version"`
+	Topic       string                  `json:"topic"`
+	PublishedAt time.Time               `json:"publishedAt"`
+	Kiosks      map[string]*KioskStatus `json:"-"`
+}
+
+type Summary struct {
+	Version     int64         `json:"version"`
+	Topic       string        `json:"topic"`
+	PublishedAt time.Time     `json:"publishedAt"`
+	Total       int           `json:"total"`
+	Applied     int           `json:"applied"`
+	Failed      int           `json:"failed"`
+	Pending     int           `json:"pending"`
+	Description string        `json:"description"`
+	Kiosks      []KioskStatus `json:"kiosks"`
+}
+
+func (r *Rollout) summary() Summary {
+	s := Summary{
+		Version:     r.Version,
+		Topic:       r.Topic,
+		PublishedAt: r.PublishedAt,
+		Total:       len(r.Kiosks),
+		Kiosks:      make([]KioskStatus, 0, len(r.Kiosks)),
+	}
+
+	for _, k := range r.Kiosks {
+		switch k.Status {
+		case kioskconfig.AckApplied:
+			s.Applied++
+		case kioskconfig.AckFailed:
+			s.Failed++
+		default:
+			s.Pending++
+		}
+
+		s.Kiosks = append(s.Kiosks, *k)
+	}
+
+	sort.Slice(
+		s.Kiosks, func(i, j int) bool {
+			return s.Kiosks[i].KioskId < s.Kiosks[j].KioskId
+		},
+	)
+
+	s.Description = fmt.Sprintf("applied on %d/%d kiosks", s.Applied, s.Total)
+	if s.Failed > 0 {
+		s.Description += fmt.Sprintf(", %d failed", s.Failed)
+	}
+
+	return s
+}
+
+// Tracker aggregates the acks of kiosks per config version.
+type Tracker struct {
+	mu       sync.RWMutex
+	rollouts map[int64]*Rollout
+	order    []int64
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{rollouts: make(map[int64]*Rollout)}
+}
+
+// Start tracks a config version published to the topic, expecting an ack from each of the kiosks.
+func (t *Tracker) Start(version int64, configTopic string, kioskIds []string) {
+	r := &Rollout{Version: version, Topic: configTopic, PublishedAt: time.Now(), Kiosks: make(map[string]*KioskStatus)}
+	for _, id := range kioskIds {
+		r.Kiosks[id] = &KioskStatus{KioskId: id, Status: StatusPending}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollouts[version] = r
+	t.order = append(t.order, version)
+
+	if len(t.order) > MaxRollouts {
+		delete(t.rollouts, t.order[0])
+		t.order = t.order[1:]
+	}
+}
+
+// Remove stops tracking a config version, such as when it failed to publish.
+func (t *Tracker) Remove(version int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.rollouts, version)
+
+	for i, v := range t.order {
+		if v == version {
+			t.order = append(t.order[:i], t.order[i+1:]...)
+
+			break
+		}
+	}
+}
+
+func (t *Tracker) HandleAck(msg *client.Message) {
+	_, kioskId, ok := topic.ParseKiosk(msg.Topic)
+	if !ok {
+		return
+	}
+
+	var ack kioskconfig.Ack
+	if err := json.Unmarshal(msg.Payload, &ack); err != nil {
+		log.Error("invalid ack", "topic", msg.Topic, "error", err)
+
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.rollouts[ack.Version]
+	if !ok {
+		log.Info("ack of untracked config", "version", ack.Version, "kiosk_id", kioskId)
+
+		return
+	}
+
+	// Kiosks that were not known when the config was published are added as they ack
+	k, ok := r.Kiosks[kioskId]
+	if !ok {
+		k = &KioskStatus{KioskId: kioskId}
+		r.Kiosks[kioskId] = k
+	}
+
+	now := time.Now()
+
+	k.Status = ack.Status
+	k.Error = ack.Error
+	k.UpdatedAt = &now
+
+	log.Info(
+		"config acked",
+		"version", ack.Version, "kiosk_id", kioskId, "status", ack.Status, "summary", r.summary().Description,
+	)
+}
+
+func (t *Tracker) Get(version int64) (Summary, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	r, ok := t.rollouts[version]
+	if !ok {
+		return Summary{}, false
+	}
+
+	return r.summary(), true
+}
+
+// List returns the summaries of the tracked rollouts, latest first.
+func (t *Tracker) List() []Summary {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	summaries := make([]Summary, 0, len(t.order))
+	for i := len(t.order) - 1; i >= 0; i-- {
+		summaries = append(summaries, t.rollouts[t.order[i]].summary())
+	}
+
+	return summaries
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go-mqtt-demo/client"
+)
+
+// Publisher publishes a message and waits for the broker to accept it.
+type Publisher func(msg *client.Message) error
+
+// Caller sends requests and waits for their responses on its own response topic.
+type Caller struct {
+	responseTopic string
+	version       client.ProtocolVersion
+	publish       Publisher
+
+	mu      sync.Mutex
+	pending map[string]chan Response
+}
+
+func NewCaller(responseTopic string, version client.ProtocolVersion, publish Publisher) *Caller {
+	return &Caller{
+		responseTopic: responseTopic,
+		version:       version,
+		publish:       publish,
+		pending:       make(map[string]chan Response),
+	}
+}
+
+func newCorrelationId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// Call publishes the request to the topic, and waits for the response until the timeout.
+func (c *Caller) Call(topic string, params json.RawMessage, timeout time.Duration) (Response, error) {
+	correlationId := newCorrelationId()
+
+	payload, err := json.Marshal(request{ResponseTopic: c.responseTopic, CorrelationId: correlationId, Params: params})
+	if err != nil {
+		return Response{}, err
+	}
+
+	msg := &client.Message{Topic: topic, Payload: payload, Qos: 1}
+
+	if c.version == client.ProtocolV5 {
+		msg.Properties = &client.Properties{
+			ResponseTopic:   c.responseTopic,
+			CorrelationData: []byte(correlationId),
+			ContentType:     "application/json",
+		}
+	}
+
+	// The pending response is registered first, since the response may arrive before the publish completes
+	res := make(chan Response, 1)
+
+	c.mu.Lock()
+	c.pending[correlationId] = res
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, correlationId)
+		c.mu.Unlock()
+	}()
+
+	if err := c.publish(msg); err != nil {
+		return Response{}, err
+	}
+
+	select {
+	case r := <-res:
+		return r, nil
+	case <-time.After(timeout):
+		return Response{}, ErrTimeout
+	}
+}
+
+// HandleResponse passes a response to the pending request with the same correlation ID.
+func (c *Caller) HandleResponse(msg *client.Message) {
+	var r Response
+	if err := json.Unmarshal(msg.Payload, &r); err != nil {
+		log.Error("invalid rpc response", "topic", msg.Topic, "error", err)
+
+		return
+	}
+
+	correlationId := r.CorrelationId
+	if msg.Properties != nil && len(msg.Properties.CorrelationData) > 0 {
+		correlationId = string(msg.Properties.CorrelationData)
+	}
+
+	c.mu.Lock()
+	res, ok := c.pending[correlationId]
+	c.mu.Unlock()
+
+	if !ok {
+		log.Info("rpc response arrived after its request timed out", "topic", msg.Topic, "correlation_id", correlationId)
+
+		return
+	}
+
+	select {
+	case res <- r:
+	default:
+	}
+}
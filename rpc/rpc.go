@@ -0,0 +1,37 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package rpc implements request/response over MQTT. The response topic and correlation ID are always in a JSON
+// envelope of the payload, so callers and servers of either protocol version work together. With MQTT 5, they are
+// also sent as properties.
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+
+	"go-mqtt-demo/logger"
+)
+
+// log logs at the level of the handler subsystem.
+var log = logger.For(logger.SubsystemHandler)
+
+var (
+	ErrTimeout       = errors.New("rpc request timed out")
+	ErrUnknownMethod = errors.New("unknown rpc method")
+)
+
+// request is the payload of a request.
+type request struct {
+	ResponseTopic string          `json:"responseTopic"`
+	CorrelationId string          `json:"correlationId"`
+	Params        json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is the payload of a response.
+type Response struct {
+	CorrelationId string          `json:"correlationId,omitempty"`
+	Result        json.RawMessage `json:"result,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
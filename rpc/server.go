@@ -0,0 +1,102 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+
+	"go-mqtt-demo/client"
+)
+
+// Method handles the params of a request, and returns a result to encode as JSON.
+type Method func(params json.RawMessage) (any, error)
+
+// Server dispatches requests to the registered methods, by the last level of the request topic.
+type Server struct {
+	publish Publisher
+
+	mu      sync.RWMutex
+	methods map[string]Method
+}
+
+func NewServer(publish Publisher) *Server {
+	return &Server{publish: publish, methods: make(map[string]Method)}
+}
+
+func (s *Server) Register(name string, method Method) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.methods[name] = method
+}
+
+func (s *Server) HandleRequest(msg *client.Message) {
+	var req request
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		log.Error("invalid rpc request", "topic", msg.Topic, "error", err)
+
+		return
+	}
+
+	// The properties of an MQTT 5 request take precedence over its envelope
+	var correlation []byte
+
+	if msg.Properties != nil && msg.Properties.ResponseTopic != "" {
+		req.ResponseTopic = msg.Properties.ResponseTopic
+		correlation = msg.Properties.CorrelationData
+	}
+
+	if req.ResponseTopic == "" {
+		log.Error("rpc request has no response topic", "topic", msg.Topic)
+
+		return
+	}
+
+	name := path.Base(msg.Topic)
+
+	// Methods may take a while, and must not block the delivery of other messages
+	go func() {
+		res := Response{CorrelationId: req.CorrelationId}
+
+		result, err := s.call(name, req.Params)
+		if err == nil {
+			res.Result, err = json.Marshal(result)
+		}
+
+		if err != nil {
+			res.Error = err.Error()
+		}
+
+		reply := &client.Message{Topic: req.ResponseTopic, Qos: 1}
+		if correlation != nil {
+			reply.Properties = &client.Properties{CorrelationData: correlation, ContentType: "application/json"}
+		}
+
+		if reply.Payload, err = json.Marshal(res); err != nil {
+			log.Error("failed to encode rpc response", "topic", msg.Topic, "error", err)
+
+			return
+		}
+
+		if err := s.publish(reply); err != nil {
+			log.Error("failed to publish rpc response", "topic", reply.Topic, "error", err)
+		}
+	}()
+}
+
+func (s *Server) call(name string, params json.RawMessage) (any, error) {
+	s.mu.RLock()
+	method, ok := s.methods[name]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownMethod, name)
+	}
+
+	return method(params)
+}
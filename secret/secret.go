@@ -0,0 +1,170 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package secret
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces a secret wherever it is formatted.
+const Redacted = "[redacted]"
+
+// The schemes of the built-in providers.
+const (
+	SchemeFile  = "file"
+	SchemeEnv   = "env"
+	SchemeVault = "vault"
+)
+
+var ErrNotFound = errors.New("secret not found")
+
+// Provider resolves the reference of a secret, e.g. the path of a file.
+type Provider interface {
+	Secret(ref string) (string, error)
+}
+
+// ProviderFunc is a function used as a Provider.
+type ProviderFunc func(ref string) (string, error)
+
+func (f ProviderFunc) Secret(ref string) (string, error) {
+	return f(ref)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{
+		SchemeFile: ProviderFunc(readFile),
+		SchemeEnv:  ProviderFunc(lookupEnv),
+	}
+)
+
+// Register adds the provider of the scheme, replacing any provider of the same scheme.
+func Register(scheme string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	providers[scheme] = p
+}
+
+func provider(scheme string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	p, ok := providers[scheme]
+
+	return p, ok
+}
+
+// readFile reads a secret from a file, without the trailing newline most editors add.
+func readFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func lookupEnv(name string) (string, error) {
+	s, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("%w: environment variable %v", ErrNotFound, name)
+	}
+
+	return s, nil
+}
+
+// Value is a secret, either a literal or a reference to a provider. A reference is resolved whenever the secret is
+// revealed, so a rotated secret is used without a restart. Formatting a literal redacts it.
+type Value struct {
+	scheme string
+	ref    string
+}
+
+// Literal returns a secret of the value itself.
+func Literal(s string) Value {
+	return Value{ref: s}
+}
+
+// Ref returns a secret resolved by the provider of the scheme.
+func Ref(scheme, ref string) Value {
+	return Value{scheme: scheme, ref: ref}
+}
+
+// Parse returns the secret of a config value. A value prefixed by the scheme of a registered provider, such as
+// file:/run/secrets/mqtt, or by vault, is a reference. Any other value is a literal. The vault is only registered
+// once a config naming it is valid, so its references are parsed before.
+func Parse(s string) Value {
+	if scheme, ref, ok := strings.Cut(s, ":"); ok {
+		if _, ok := provider(scheme); ok || scheme == SchemeVault {
+			return Ref(scheme, ref)
+		}
+	}
+
+	return Literal(s)
+}
+
+func (v Value) IsZero() bool {
+	return v == Value{}
+}
+
+// Scheme returns the scheme of a reference, or an empty string for a literal.
+func (v Value) Scheme() string {
+	return v.scheme
+}
+
+// Reveal returns the secret, resolving a reference with its provider.
+func (v Value) Reveal() (string, error) {
+	return v.RevealWith(nil)
+}
+
+// RevealWith returns the secret like Reveal, but resolves a reference with the provider of its scheme in providers if
+// any, e.g. a vault that is not registered yet.
+func (v Value) RevealWith(providers map[string]Provider) (string, error) {
+	if v.scheme == "" {
+		return v.ref, nil
+	}
+
+	p, ok := providers[v.scheme]
+	if !ok {
+		p, ok = provider(v.scheme)
+	}
+
+	if !ok {
+		return "", fmt.Errorf("no secret provider for %q", v.scheme)
+	}
+
+	s, err := p.Secret(v.ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %v: %w", v, err)
+	}
+
+	return s, nil
+}
+
+// String returns the reference of the secret, which is not secret itself, or redacts a literal.
+func (v Value) String() string {
+	switch {
+	case v.IsZero():
+		return ""
+	case v.scheme == "":
+		return Redacted
+	default:
+		return v.scheme + ":" + v.ref
+	}
+}
+
+func (v Value) GoString() string {
+	return v.String()
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
@@ -0,0 +1,267 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// The scrypt parameters recommended for interactive logins, deriving a 256-bit AES key.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	keyLen       = 32
+	saltLen      = 16
+	vaultVersion = 1
+)
+
+var ErrWrongPassphrase = errors.New("wrong vault passphrase or corrupted vault")
+
+// vaultFile is the format of the vault file. The secrets are encrypted as a JSON object with AES-256-GCM, with a key
+// derived from the passphrase by scrypt.
+type vaultFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Vault is a local file of secrets encrypted with a passphrase. The file is decrypted again once it changes, so
+// secrets rotate without a restart.
+type Vault struct {
+	path       string
+	passphrase Value
+
+	mu      sync.Mutex
+	modTime time.Time
+	secrets map[string]string
+}
+
+func NewVault(path string, passphrase Value) *Vault {
+	return &Vault{path: path, passphrase: passphrase}
+}
+
+// Secret returns the secret of the name.
+func (v *Vault) Secret(name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.load(); err != nil {
+		return "", err
+	}
+
+	s, ok := v.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %v in vault %v", ErrNotFound, name, v.path)
+	}
+
+	return s, nil
+}
+
+// Names returns the names of the secrets in order.
+func (v *Vault) Names() ([]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.load(); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(v.secrets))
+	for name := range v.secrets {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// Set stores the secret of the name, creating the vault if it does not exist.
+func (v *Vault) Set(name, secret string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if v.secrets == nil {
+		v.secrets = make(map[string]string)
+	}
+
+	v.secrets[name] = secret
+
+	return v.save()
+}
+
+// Delete removes the secret of the name.
+func (v *Vault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.load(); err != nil {
+		return err
+	}
+
+	if _, ok := v.secrets[name]; !ok {
+		return fmt.Errorf("%w: %v in vault %v", ErrNotFound, name, v.path)
+	}
+
+	delete(v.secrets, name)
+
+	return v.save()
+}
+
+// load decrypts the vault file, unless it is unchanged since it was loaded last.
+func (v *Vault) load() error {
+	info, err := os.Stat(v.path)
+	if err != nil {
+		return err
+	}
+
+	if v.secrets != nil && info.ModTime().Equal(v.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(v.path)
+	if err != nil {
+		return err
+	}
+
+	var f vaultFile
+
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("invalid vault %v: %w", v.path, err)
+	}
+
+	if f.Version != vaultVersion {
+		return fmt.Errorf("unsupported vault version %v", f.Version)
+	}
+
+	gcm, err := v.cipher(f.Salt)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := gcm.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+
+	var secrets map[string]string
+
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return fmt.Errorf("invalid vault %v: %w", v.path, err)
+	}
+
+	v.secrets = secrets
+	v.modTime = info.ModTime()
+
+	return nil
+}
+
+// save encrypts the secrets with a new salt and nonce, and replaces the vault file atomically.
+func (v *Vault) save() error {
+	plaintext, err := json.Marshal(v.secrets)
+	if err != nil {
+		return err
+	}
+
+	f := vaultFile{Version: vaultVersion, Salt: make([]byte, saltLen)}
+
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+
+	gcm, err := v.cipher(f.Salt)
+	if err != nil {
+		return err
+	}
+
+	f.Nonce = make([]byte, gcm.NonceSize())
+
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+
+	f.Ciphertext = gcm.Seal(nil, f.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(v.path), filepath.Base(v.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), v.path); err != nil {
+		return err
+	}
+
+	// The next load decrypts the file again, to pick up its modification time
+	v.secrets = nil
+
+	return nil
+}
+
+func (v *Vault) cipher(salt []byte) (cipher.AEAD, error) {
+	passphrase, err := v.passphrase.Reveal()
+	if err != nil {
+		return nil, err
+	}
+
+	if passphrase == "" {
+		return nil, errors.New("vault passphrase is required")
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,229 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package shadow models the state of kiosks as device shadows: admin writes the desired state, kiosks report their
+// actual state, and the delta is what remains to be applied.
+package shadow
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"go-mqtt-demo/client"
+	"go-mqtt-demo/logger"
+	"go-mqtt-demo/topic"
+)
+
+// log logs at the level of the handler subsystem.
+var log = logger.For(logger.SubsystemHandler)
+
+const (
+	Desired  = "desired"
+	Reported = "reported"
+	Delta    = "delta"
+)
+
+// Document is one of the states of a shadow. Versions are incremented by the writer of the document.
+type Document struct {
+	State     map[string]any `json:"state"`
+	Version   int64          `json:"version"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// Shadow holds the documents of a kiosk. A nil document has not been received yet, and a nil delta means the
+// reported state matches the desired state.
+type Shadow struct {
+	LocationId string    `json:"locationId"`
+	KioskId    string    `json:"kioskId"`
+	Desired    *Document `json:"desired"`
+	Reported   *Document `json:"reported"`
+	Delta      *Document `json:"delta"`
+}
+
+// ComputeDelta returns the desired values that differ from the reported values. Objects are compared recursively,
+// and reported keys that are not desired are ignored.
+func ComputeDelta(desired, reported map[string]any) map[string]any {
+	delta := make(map[string]any)
+
+	for k, d := range desired {
+		r, ok := reported[k]
+
+		dObj, dIsObj := d.(map[string]any)
+		rObj, rIsObj := r.(map[string]any)
+
+		if dIsObj && rIsObj {
+			if sub := ComputeDelta(dObj, rObj); len(sub) > 0 {
+				delta[k] = sub
+			}
+
+			continue
+		}
+
+		if !ok || !reflect.DeepEqual(d, r) {
+			delta[k] = d
+		}
+	}
+
+	return delta
+}
+
+func (s *Shadow) updateDelta() {
+	if s.Desired == nil {
+		s.Delta = nil
+
+		return
+	}
+
+	var reported map[string]any
+	if s.Reported != nil {
+		reported = s.Reported.State
+	}
+
+	delta := ComputeDelta(s.Desired.State, reported)
+	if len(delta) == 0 {
+		s.Delta = nil
+
+		return
+	}
+
+	s.Delta = &Document{State: delta, Version: s.Desired.Version, UpdatedAt: time.Now()}
+}
+
+// Registry keeps the shadows of kiosks from their desired and reported documents.
+type Registry struct {
+	mu          sync.RWMutex
+	shadows     map[string]*Shadow
+	subscribers map[chan Shadow]struct{}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		shadows:     make(map[string]*Shadow),
+		subscribers: make(map[chan Shadow]struct{}),
+	}
+}
+
+// Handle updates the desired or reported document of the kiosk from the topic of the message. It returns the
+// updated shadow, and whether it changed.
+func (r *Registry) Handle(msg *client.Message, name string) (Shadow, bool) {
+	locationId, kioskId, ok := topic.ParseKiosk(msg.Topic)
+	if !ok {
+		return Shadow{}, false
+	}
+
+	var doc Document
+	if err := json.Unmarshal(msg.Payload, &doc); err != nil {
+		log.Error("invalid shadow document", "document", name, "topic", msg.Topic, "error", err)
+
+		return Shadow{}, false
+	}
+
+	return r.Set(locationId, kioskId, name, doc)
+}
+
+// Set replaces the desired or reported document of a kiosk, unless it is not newer than the current one. It returns
+// the updated shadow, and whether it changed.
+func (r *Registry) Set(locationId, kioskId, name string, doc Document) (Shadow, bool) {
+	r.mu.Lock()
+
+	s, ok := r.shadows[locationId+"/"+kioskId]
+	if !ok {
+		s = &Shadow{LocationId: locationId, KioskId: kioskId}
+		r.shadows[locationId+"/"+kioskId] = s
+	}
+
+	current := &s.Desired
+	if name == Reported {
+		current = &s.Reported
+	}
+
+	// Documents published by this process are received again from the broker
+	if *current != nil && doc.Version <= (*current).Version {
+		update := *s
+		r.mu.Unlock()
+
+		return update, false
+	}
+
+	if doc.State == nil {
+		doc.State = make(map[string]any)
+	}
+
+	*current = &doc
+	s.updateDelta()
+
+	update := *s
+
+	r.mu.Unlock()
+
+	r.publish(update)
+
+	return update, true
+}
+
+func (r *Registry) publish(s Shadow) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for sub := range r.subscribers {
+		select {
+		case sub <- s:
+		default:
+			log.Error("shadow subscriber is too slow, dropping update", "kiosk_id", s.KioskId)
+		}
+	}
+}
+
+// Get returns the shadow of a kiosk. A kiosk without documents has an empty shadow.
+func (r *Registry) Get(locationId, kioskId string) Shadow {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if s, ok := r.shadows[locationId+"/"+kioskId]; ok {
+		return *s
+	}
+
+	return Shadow{LocationId: locationId, KioskId: kioskId}
+}
+
+// List returns all shadows, ordered by location and kiosk ID.
+func (r *Registry) List() []Shadow {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	shadows := make([]Shadow, 0, len(r.shadows))
+	for _, s := range r.shadows {
+		shadows = append(shadows, *s)
+	}
+
+	sort.Slice(
+		shadows, func(i, j int) bool {
+			if shadows[i].LocationId != shadows[j].LocationId {
+				return shadows[i].LocationId < shadows[j].LocationId
+			}
+
+			return shadows[i].KioskId < shadows[j].KioskId
+		},
+	)
+
+	return shadows
+}
+
+// Subscribe returns a chan of shadow changes. The returned func must be called to unsubscribe.
+func (r *Registry) Subscribe() (<-chan Shadow, func()) {
+	sub := make(chan Shadow, client.DefaultBufferSize)
+
+	r.mu.Lock()
+	r.subscribers[sub] = struct{}{}
+	r.mu.Unlock()
+
+	return sub, func() {
+		r.mu.Lock()
+		delete(r.subscribers, sub)
+		r.mu.Unlock()
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package shadow
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeDelta(t *testing.T) {
+	tests := []struct {
+		name     string
+		desired  map[string]any
+		reported map[string]any
+		want     map[string]any
+	}{
+		{
+			name:     "in sync",
+			desired:  map[string]any{"brightness": 60.0},
+			reported: map[string]any{"brightness": 60.0},
+			want:     map[string]any{},
+		},
+		{
+			name:     "changed value",
+			desired:  map[string]any{"brightness": 60.0, "volume": 5.0},
+			reported: map[string]any{"brightness": 80.0, "volume": 5.0},
+			want:     map[string]any{"brightness": 60.0},
+		},
+		{
+			name:     "value not reported",
+			desired:  map[string]any{"brightness": 60.0},
+			reported: map[string]any{},
+			want:     map[string]any{"brightness": 60.0},
+		},
+		{
+			name:     "reported keys not desired ignored",
+			desired:  map[string]any{"brightness": 60.0},
+			reported: map[string]any{"brightness": 60.0, "uptime": 3600.0},
+			want:     map[string]any{},
+		},
+		{
+			name: "nested changed, added and in sync",
+			desired: map[string]any{
+				"display": map[string]any{"brightness": 60.0, "theme": "dark", "timeout": 30.0},
+			},
+			reported: map[string]any{
+				"display": map[string]any{"brightness": 80.0, "theme": "dark", "size": 24.0},
+			},
+			want: map[string]any{"display": map[string]any{"brightness": 60.0, "timeout": 30.0}},
+		},
+		{
+			name:     "nested in sync",
+			desired:  map[string]any{"display": map[string]any{"theme": "dark"}},
+			reported: map[string]any{"display": map[string]any{"theme": "dark", "size": 24.0}},
+			want:     map[string]any{},
+		},
+		{
+			name:     "object desired, value reported",
+			desired:  map[string]any{"display": map[string]any{"theme": "dark"}},
+			reported: map[string]any{"display": "off"},
+			want:     map[string]any{"display": map[string]any{"theme": "dark"}},
+		},
+		{
+			name:     "arrays compared as a whole",
+			desired:  map[string]any{"langs": []any{"en", "fr"}},
+			reported: map[string]any{"langs": []any{"en"}},
+			want:     map[string]any{"langs": []any{"en", "fr"}},
+		},
+		{
+			name:     "desired null",
+			desired:  map[string]any{"brightness": nil},
+			reported: map[string]any{"brightness": 60.0},
+			want:     map[string]any{"brightness": nil},
+		},
+		{
+			name:     "nothing reported",
+			desired:  map[string]any{"brightness": 60.0},
+			reported: nil,
+			want:     map[string]any{"brightness": 60.0},
+		},
+		{
+			name:     "nothing desired",
+			desired:  nil,
+			reported: map[string]any{"brightness": 60.0},
+			want:     map[string]any{},
+		},
+		{
+			name:     "empty documents",
+			desired:  map[string]any{},
+			reported: map[string]any{},
+			want:     map[string]any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := ComputeDelta(tt.desired, tt.reported); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("delta %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package topic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Match reports whether the topic matches the subscription filter, including the + and # wildcards. A filter starting
+// with a wildcard does not match topics starting with $, such as $SYS/broker/uptime.
+func Match(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+
+	fs := strings.Split(filter, "/")
+	ts := strings.Split(topic, "/")
+
+	for i, f := range fs {
+		switch {
+		case f == "#":
+			return true
+		case i >= len(ts):
+			return false
+		case f == "+":
+			continue
+		case f != ts[i]:
+			return false
+		}
+	}
+
+	return len(fs) == len(ts)
+}
+
+var ErrInvalidFilter = errors.New("invalid topic filter")
+
+// ValidateFilter checks that the wildcards of a subscription filter are used as the MQTT specification requires.
+func ValidateFilter(filter string) error {
+	if filter == "" {
+		return ErrInvalidFilter
+	}
+
+	levels := strings.Split(filter, "/")
+
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return ErrInvalidFilter
+		}
+
+		if strings.Contains(level, "+") && level != "+" {
+			return ErrInvalidFilter
+		}
+	}
+
+	return nil
+}
+
+// KioskStatus is the topic of the retained presence status of a kiosk. Use + as the kiosk ID to match all kiosks.
+func KioskStatus(locationId, kioskId string) string {
+	return fmt.Sprintf("location/%v/kiosk/%v/status", locationId, kioskId)
+}
+
+// KioskAnnounce is the topic of the retained announcement of a kiosk. Use + as the kiosk ID to match all kiosks.
+func KioskAnnounce(locationId, kioskId string) string {
+	return fmt.Sprintf("location/%v/kiosk/%v/announce", locationId, kioskId)
+}
+
+// LocationConfig is the topic of the config for all kiosks in a location.
+func LocationConfig(locationId string) string {
+	return fmt.Sprintf("location/%v/kiosk/config", locationId)
+}
+
+// GroupConfig is the topic of the config for a group of kiosks in a location.
+func GroupConfig(locationId, group string) string {
+	return fmt.Sprintf("location/%v/group/%v/config", locationId, group)
+}
+
+// KioskConfig is the topic of the config for a single kiosk.
+func KioskConfig(locationId, kioskId string) string {
+	return fmt.Sprintf("location/%v/kiosk/%v/config", locationId, kioskId)
+}
+
+// KioskConfigAck is the topic on which a kiosk acks the configs it applied. Use + as the kiosk ID to match all
+// kiosks.
+func KioskConfigAck(locationId, kioskId string) string {
+	return fmt.Sprintf("location/%v/kiosk/%v/config/ack", locationId, kioskId)
+}
+
+// KioskShadow is the topic of a shadow document of a kiosk: desired, reported or delta.
+func KioskShadow(locationId, kioskId, document string) string {
+	return fmt.Sprintf("location/%v/kiosk/%v/shadow/%v", locationId, kioskId, document)
+}
+
+// KioskRPC is the topic of the RPC requests of a method to a kiosk. Use + as the method to match all methods.
+func KioskRPC(locationId, kioskId, method string) string {
+	return fmt.Sprintf("location/%v/kiosk/%v/rpc/%v", locationId, kioskId, method)
+}
+
+// AdminRPCResponse is the topic on which an admin instance receives the RPC responses of kiosks.
+func AdminRPCResponse(locationId, adminId string) string {
+	return fmt.Sprintf("location/%v/admin/%v/rpc/response", locationId, adminId)
+}
+
+// ParseKiosk returns the location and kiosk IDs of a topic under location/{loc}/kiosk/{id}/.
+func ParseKiosk(topic string) (locationId, kioskId string, ok bool) {
+	levels := strings.Split(topic, "/")
+	if len(levels) < 5 || levels[0] != "location" || levels[2] != "kiosk" {
+		return "", "", false
+	}
+
+	return levels[1], levels[3], true
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package topic
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		topic  string
+		want   bool
+	}{
+		{"exact", "location/1/kiosk/2/status", "location/1/kiosk/2/status", true},
+		{"different level", "location/1/kiosk/2/status", "location/1/kiosk/3/status", false},
+		{"single level", "location/1/kiosk/+/status", "location/1/kiosk/2/status", true},
+		{"single level is not multiple", "location/1/kiosk/+/status", "location/1/kiosk/2/3/status", false},
+		{"single level is not none", "location/1/kiosk/+", "location/1/kiosk", false},
+		{"single level matches empty level", "location/+/kiosk", "location//kiosk", true},
+		{"multi level", "location/1/kiosk/+/sensor/#", "location/1/kiosk/2/sensor/t/1", true},
+		{"multi level matches parent", "location/1/#", "location/1", true},
+		{"multi level alone", "#", "location/1/kiosk/2/status", true},
+		{"longer topic", "location/1", "location/1/kiosk", false},
+		{"shorter topic", "location/1/kiosk", "location/1", false},
+		{"leading slash", "+/location", "/location", true},
+		{"multi level skips $ topics", "#", "$SYS/broker/uptime", false},
+		{"single level skips $ topics", "+/broker/uptime", "$SYS/broker/uptime", false},
+		{"$ topic by name", "$SYS/#", "$SYS/broker/uptime", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := Match(tt.filter, tt.topic); got != tt.want {
+					t.Errorf("Match(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		valid  bool
+	}{
+		{"topic", "location/1/kiosk/2/status", true},
+		{"single level", "location/+/kiosk/+/status", true},
+		{"multi level", "location/1/#", true},
+		{"multi level alone", "#", true},
+		{"single level alone", "+", true},
+		{"empty", "", false},
+		{"multi level not last", "location/#/kiosk", false},
+		{"multi level within level", "location/1#", false},
+		{"single level within level", "location/kiosk+/status", false},
+		{"multi level twice", "#/#", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				err := ValidateFilter(tt.filter)
+				if tt.valid && err != nil {
+					t.Errorf("ValidateFilter(%q) = %v, want nil", tt.filter, err)
+				}
+
+				if !tt.valid && !errors.Is(err, ErrInvalidFilter) {
+					t.Errorf("ValidateFilter(%q) = %v, want %v", tt.filter, err, ErrInvalidFilter)
+				}
+			},
+		)
+	}
+}
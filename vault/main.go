@@ -0,0 +1,71 @@
+// Copyright 2025 Jon Perada. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Command vault manages the local vault of secrets referenced by vault:name in the config of admin and kiosks.
+//
+//	vault [-file secrets.vault] set name < secret
+//	vault [-file secrets.vault] list
+//	vault [-file secrets.vault] delete name
+//
+// The passphrase is read from SECRETS_VAULT_PASSWORD, or the file of SECRETS_VAULT_PASSWORD_FILE.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"go-mqtt-demo/secret"
+)
+
+func main() {
+	file := flag.String("file", "secrets.vault", "vault `file`")
+	flag.Parse()
+
+	if err := run(*file, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(file string, args []string) error {
+	passphrase := secret.Literal(os.Getenv("SECRETS_VAULT_PASSWORD"))
+	if path := os.Getenv("SECRETS_VAULT_PASSWORD_FILE"); path != "" {
+		passphrase = secret.Ref(secret.SchemeFile, path)
+	}
+
+	v := secret.NewVault(file, passphrase)
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: vault [-file path] set|list|delete [name]")
+	}
+
+	switch cmd := args[0]; {
+	case cmd == "list" && len(args) == 1:
+		names, err := v.Names()
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			fmt.Println(name)
+		}
+
+		return nil
+	case cmd == "set" && len(args) == 2:
+		// The secret is read from stdin, so it does not end up in the shell history
+		s, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && s == "" {
+			return fmt.Errorf("failed to read secret from stdin: %w", err)
+		}
+
+		return v.Set(args[1], strings.TrimRight(s, "\r\n"))
+	case cmd == "delete" && len(args) == 2:
+		return v.Delete(args[1])
+	default:
+		return fmt.Errorf("usage: vault [-file path] set|list|delete [name]")
+	}
+}